@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const adminPrefix = "/__admin/"
+
+// endpointInfo 是管理接口中展示的单个端点信息
+type endpointInfo struct {
+	Service      string `json:"service"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	ResponseFile string `json:"responseFile,omitempty"`
+	StatusCode   int    `json:"statusCode"`
+}
+
+// newAdminRouter 创建管理接口路由。它独立于用户路由，不会被重载替换，也不会被用户端点覆盖
+func newAdminRouter(h *routerHandler) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	r.GET(versionPath, func(c *gin.Context) {
+		c.JSON(http.StatusOK, currentBuildInfo())
+	})
+	r.GET(adminPrefix+"endpoints", func(c *gin.Context) {
+		config := h.activeConfig()
+		endpoints := []endpointInfo{}
+		for _, service := range config.Services {
+			for _, endpoint := range service.Endpoints {
+				statusCode := endpoint.StatusCode
+				if statusCode == 0 {
+					statusCode = 200
+				}
+				endpoints = append(endpoints, endpointInfo{
+					Service:      service.Name,
+					Method:       endpoint.Method,
+					Path:         service.BasePath + endpoint.Path,
+					ResponseFile: endpoint.ResponseFile,
+					StatusCode:   statusCode,
+				})
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"endpoints": endpoints})
+	})
+
+	r.POST(adminPrefix+"reload", func(c *gin.Context) {
+		if err := h.reload(); err != nil {
+			log.Printf("Failed to reload config: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "reloaded"})
+	})
+
+	// 重置序列端点，可用 method/path 参数只重置指定端点
+	r.POST(adminPrefix+"sequences/reset", func(c *gin.Context) {
+		count := sequences.reset(c.Query("method"), c.Query("path"))
+		c.JSON(http.StatusOK, gin.H{"reset": count})
+	})
+
+	// 重置 callLimit 端点的调用计数，参数与 sequences/reset 相同
+	r.POST(adminPrefix+"calllimits/reset", func(c *gin.Context) {
+		count := callCounters.reset(c.Query("method"), c.Query("path"))
+		c.JSON(http.StatusOK, gin.H{"reset": count})
+	})
+
+	// 重置场景会话，可用 name 只重置指定场景，用 session 只重置指定会话
+	r.POST(adminPrefix+"scenarios/reset", func(c *gin.Context) {
+		session, hasSession := c.GetQuery("session")
+		count := scenarios.reset(c.Query("name"), session, !hasSession)
+		c.JSON(http.StatusOK, gin.H{"reset": count})
+	})
+
+	// 导出和恢复序列、场景等状态，快照格式见 stateSnapshot
+	r.GET(adminPrefix+"state", func(c *gin.Context) {
+		c.JSON(http.StatusOK, takeStateSnapshot())
+	})
+	r.PUT(adminPrefix+"state", func(c *gin.Context) {
+		var snapshot stateSnapshot
+		if err := c.ShouldBindJSON(&snapshot); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := restoreStateSnapshot(snapshot, h.activeConfig()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "restored", "sequences": len(snapshot.Sequences), "scenarios": len(snapshot.Scenarios)})
+	})
+
+	// 查询和清空请求记录，用于在集成测试中断言 mock 被调用的次数和内容
+	r.GET(adminPrefix+"requests", func(c *gin.Context) {
+		calls := h.calls.list(c.Query("method"), c.Query("path"))
+		c.JSON(http.StatusOK, gin.H{"count": len(calls), "requests": calls})
+	})
+	r.DELETE(adminPrefix+"requests", func(c *gin.Context) {
+		h.calls.reset()
+		c.Status(http.StatusNoContent)
+	})
+
+	return r
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthConfig 为服务下的所有端点开启认证，type 为 basic 或 bearer
+type AuthConfig struct {
+	Type     string `yaml:"type" json:"type" toml:"type"`
+	Username string `yaml:"username" json:"username" toml:"username"`
+	Password string `yaml:"password" json:"password" toml:"password"`
+	Token    string `yaml:"token" json:"token" toml:"token"`
+}
+
+func (a *AuthConfig) validate() error {
+	switch strings.ToLower(a.Type) {
+	case "basic":
+		if a.Username == "" {
+			return fmt.Errorf("basic auth requires username")
+		}
+	case "bearer":
+		if a.Token == "" {
+			return fmt.Errorf("bearer auth requires token")
+		}
+	default:
+		return fmt.Errorf("unsupported auth type %q, expected basic or bearer", a.Type)
+	}
+	return nil
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// authMiddleware 校验请求凭证，失败时返回 401 和 WWW-Authenticate
+func authMiddleware(cfg *AuthConfig, realm string) gin.HandlerFunc {
+	authType := strings.ToLower(cfg.Type)
+	return func(c *gin.Context) {
+		switch authType {
+		case "basic":
+			user, pass, ok := c.Request.BasicAuth()
+			if ok && secureEqual(user, cfg.Username) && secureEqual(pass, cfg.Password) {
+				return
+			}
+			c.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+		case "bearer":
+			header := c.GetHeader("Authorization")
+			if token, ok := strings.CutPrefix(header, "Bearer "); ok && secureEqual(token, cfg.Token) {
+				return
+			}
+			c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", realm))
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	}
+}
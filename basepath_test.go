@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+const globalBasePathConfig = `
+server:
+  basePath: /v1/
+services:
+  - name: users
+    basePath: /users
+    endpoints:
+      - path: /list
+        method: GET
+        responseBody: users
+  - name: root
+    basePath: ""
+    endpoints:
+      - path: /ping
+        method: GET
+        responseBody: pong
+`
+
+func TestGlobalBasePath(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": globalBasePathConfig}, routerOptions{})
+	expectBody(t, s.get("/v1/users/list"), "users")
+	expectBody(t, s.get("/v1/ping"), "pong")
+	expectStatus(t, s.get("/users/list"), 404)
+	expectStatus(t, s.get("/ping"), 404)
+}
+
+func TestGlobalBasePathBuiltins(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": globalBasePathConfig}, routerOptions{})
+	s.handler.healthPath = "/__health"
+	// 默认内置接口保持在根路径
+	expectStatus(t, s.get("/__health"), 200)
+	expectStatus(t, s.get("/v1/__health"), 404)
+
+	s.handler.prefixBuiltins = true
+	expectStatus(t, s.get("/v1/__health"), 200)
+	expectStatus(t, s.get("/__health"), 404)
+	expectBody(t, s.get("/v1/ping"), "pong")
+}
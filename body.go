@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestBodyKey    = "mock.requestBody"
+	requestBodyErrKey = "mock.requestBodyErr"
+)
+
+// readRequestBody 读取并缓存请求体，同时重置 Body 以便后续继续读取
+func readRequestBody(c *gin.Context) ([]byte, error) {
+	if cached, ok := c.Get(requestBodyKey); ok {
+		if err, ok := c.Get(requestBodyErrKey); ok {
+			return cached.([]byte), err.(error)
+		}
+		return cached.([]byte), nil
+	}
+
+	var body []byte
+	var err error
+	if c.Request.Body != nil {
+		body, err = io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	c.Set(requestBodyKey, body)
+	if err != nil {
+		c.Set(requestBodyErrKey, err)
+	}
+	return body, err
+}
+
+// bodyLimit 限制请求体大小，超过 maxBytes 时返回 413
+func bodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+			return
+		}
+		if c.Request.Body == nil {
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		if _, err := readRequestBody(c); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+				return
+			}
+		}
+	}
+}
+
+// echoRequest 原样返回请求体，并保留请求的 Content-Type
+func echoRequest(c *gin.Context) {
+	body, err := readRequestBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	contentType := c.GetHeader("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+func isEchoMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const bodyLimitConfig = `
+server:
+  maxBodyBytes: 10
+@RECORD@services:
+  - name: svc
+    basePath: /
+    endpoints:
+      - path: /echo
+        method: POST
+        echo: true
+`
+
+// 调用记录和 recordDir 都会读取请求体，必须在 bodyLimit 之后执行，否则超限的分块请求会绕过限制
+func TestBodyLimitWithCallRecorder(t *testing.T) {
+	files := map[string]string{"config.yaml": strings.Replace(bodyLimitConfig, "@RECORD@", "", 1)}
+	s := newTestServer(t, files, routerOptions{Calls: newCallRecorder(10)})
+	expectStatus(t, s.do(http.MethodPost, "/echo", chunkedBody(100)), http.StatusRequestEntityTooLarge)
+	expectStatus(t, s.do(http.MethodPost, "/echo", chunkedBody(5)), http.StatusOK)
+}
+
+func TestBodyLimitWithRecordDir(t *testing.T) {
+	files := map[string]string{"config.yaml": strings.Replace(bodyLimitConfig, "@RECORD@", "  recordDir: @DIR@/recorded\n", 1)}
+	s := newTestServer(t, files, routerOptions{})
+	expectStatus(t, s.do(http.MethodPost, "/echo", chunkedBody(100)), http.StatusRequestEntityTooLarge)
+	expectStatus(t, s.do(http.MethodPost, "/echo", chunkedBody(5)), http.StatusOK)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cachedResponse 是缓存下来的完整响应，只包含端点处理函数自己设置的响应头
+type cachedResponse struct {
+	status  int
+	header  http.Header
+	body    []byte
+	created time.Time
+}
+
+// uncachedHeaders 由外层中间件按每个请求重新决定，例如 gzip 是否压缩取决于请求的 Accept-Encoding
+var uncachedHeaders = map[string]bool{
+	"X-Cache":          true,
+	"Content-Encoding": true,
+	"Content-Length":   true,
+}
+
+// cacheEntry 在第一个请求生成响应期间，让同一 key 的其他请求等待结果而不是重复生成
+type cacheEntry struct {
+	ready chan struct{}
+	resp  *cachedResponse
+}
+
+// responseCache 按方法和请求 URI 缓存端点响应。缓存随处理函数一起创建，配置重载后自然失效；
+// 端点引用的响应文件变化时由 cacheIndex 单独清空
+type responseCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[string]*cacheEntry)}
+}
+
+// clear 丢弃所有条目。正在生成的响应仍会交给等待它的请求，但不再被后续请求命中
+func (cache *responseCache) clear() {
+	cache.mu.Lock()
+	cache.entries = make(map[string]*cacheEntry)
+	cache.mu.Unlock()
+}
+
+// cacheIndex 记录每个响应文件被哪些端点的缓存引用。响应文件在每次请求时读取，
+// 文件变化时不需要重载配置，只需清空引用它的缓存
+type cacheIndex struct {
+	mu     sync.Mutex
+	byFile map[string][]*responseCache
+}
+
+func newCacheIndex() *cacheIndex {
+	return &cacheIndex{byFile: make(map[string][]*responseCache)}
+}
+
+// register 记录 cache 依赖的响应文件，index 为 nil 时不做任何事
+func (index *cacheIndex) register(cache *responseCache, files []string) {
+	if index == nil {
+		return
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for _, file := range files {
+		file = filepath.Clean(file)
+		index.byFile[file] = append(index.byFile[file], cache)
+	}
+}
+
+// invalidate 清空引用 file 的所有缓存，返回清空的缓存数量
+func (index *cacheIndex) invalidate(file string) int {
+	if index == nil {
+		return 0
+	}
+	index.mu.Lock()
+	caches := index.byFile[filepath.Clean(file)]
+	index.mu.Unlock()
+	for _, cache := range caches {
+		cache.clear()
+	}
+	return len(caches)
+}
+
+// uncacheableBy 返回端点响应依赖的、缓存 key 之外的请求输入，没有时返回空字符串。
+// 缓存只按方法和请求 URI 区分，按 Accept、请求头、请求体或场景状态生成的响应会被错误地共享给其他请求
+func uncacheableBy(endpoint Endpoint) string {
+	if len(endpoint.Variants) > 0 {
+		return "selects variants by the Accept header"
+	}
+	for i, rule := range endpoint.Matches {
+		if len(rule.When.Headers) > 0 {
+			return fmt.Sprintf("match rule %d depends on request headers", i)
+		}
+		if len(rule.When.Body) > 0 {
+			return fmt.Sprintf("match rule %d depends on the request body", i)
+		}
+	}
+	if endpoint.Scenario != nil {
+		return fmt.Sprintf("depends on the state of scenario %q", endpoint.Scenario.Name)
+	}
+	switch {
+	case endpoint.Echo:
+		return "echoes the request body"
+	case len(endpoint.Reflect) > 0:
+		return "reflects fields of the request body"
+	case endpoint.Script != "":
+		return "runs a script that can read request headers and body"
+	case endpoint.Type == endpointTypeExec:
+		return "runs a command that receives the request headers and body"
+	}
+	// auto 模式下 .gz 文件按 Accept-Encoding 决定是否原样返回压缩数据
+	if endpoint.GzipMode == "" || endpoint.GzipMode == gzipModeAuto {
+		for _, file := range endpointFiles(endpoint) {
+			if file != endpoint.Schema && isGzipFile(file) {
+				return fmt.Sprintf("serves gzip file %s according to Accept-Encoding (use gzipMode decompress or raw)", file)
+			}
+		}
+	}
+	// 读取 .Headers 或 .Body 的模板由 validateTemplates 在解析模板后检查
+	return ""
+}
+
+// withCache 在 ttl 内直接返回缓存的响应，不再读取文件或渲染模板，也不再执行延迟和故障注入。
+// 只缓存 2xx 响应；响应头 X-Cache 标明是否命中
+func withCache(cache *responseCache, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Request.Method + " " + c.Request.URL.RequestURI()
+		for {
+			cache.mu.Lock()
+			e, ok := cache.entries[key]
+			if ok && e.resp != nil && time.Since(e.resp.created) >= cache.ttl {
+				delete(cache.entries, key)
+				ok = false
+			}
+			if !ok {
+				cache.sweep()
+				e = &cacheEntry{ready: make(chan struct{})}
+				cache.entries[key] = e
+				cache.mu.Unlock()
+				cache.fill(c, key, e, next)
+				return
+			}
+			cache.mu.Unlock()
+
+			select {
+			case <-e.ready:
+			case <-c.Request.Context().Done():
+				return
+			}
+			if e.resp != nil {
+				e.resp.replay(c)
+				return
+			}
+			// 生成响应的请求没有得到可缓存的结果，重新竞争生成
+		}
+	}
+}
+
+// sweep 删除已过期的条目，避免不同查询参数的请求让缓存无限增长，调用方需持有 mu
+func (cache *responseCache) sweep() {
+	for key, e := range cache.entries {
+		if e.resp != nil && time.Since(e.resp.created) >= cache.ttl {
+			delete(cache.entries, key)
+		}
+	}
+}
+
+// fill 执行处理函数并在结果可缓存时保存，完成后唤醒等待的请求
+func (cache *responseCache) fill(c *gin.Context, key string, e *cacheEntry, next gin.HandlerFunc) {
+	before := c.Writer.Header().Clone()
+	w := &teeWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Header("X-Cache", "MISS")
+	defer func() {
+		c.Writer = w.ResponseWriter
+		cache.mu.Lock()
+		// 期间缓存可能已被 clear，只删除自己的条目
+		if e.resp == nil && cache.entries[key] == e {
+			delete(cache.entries, key)
+		}
+		cache.mu.Unlock()
+		close(e.ready)
+	}()
+
+	next(c)
+
+	status := c.Writer.Status()
+	if status < 200 || status >= 300 || c.Request.Context().Err() != nil || c.GetBool(timedOutKey) {
+		return
+	}
+	header := make(http.Header)
+	for k, v := range c.Writer.Header() {
+		if uncachedHeaders[k] || slices.Equal(before[k], v) {
+			continue
+		}
+		header[k] = slices.Clone(v)
+	}
+	cache.mu.Lock()
+	e.resp = &cachedResponse{status: status, header: header, body: w.body.Bytes(), created: time.Now()}
+	cache.mu.Unlock()
+}
+
+func (r *cachedResponse) replay(c *gin.Context) {
+	h := c.Writer.Header()
+	for k, v := range r.header {
+		h[k] = slices.Clone(v)
+	}
+	h.Set("X-Cache", "HIT")
+	h.Set("Age", strconv.Itoa(int(time.Since(r.created).Seconds())))
+	if etag := r.header.Get("ETag"); etag != "" {
+		if inm := c.GetHeader("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.Status(r.status)
+	c.Writer.Write(r.body)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 缓存 key 只有方法和请求 URI，依赖其他请求输入的端点必须在加载时被拒绝
+func TestCacheTTLRejectsInputsOutsideKey(t *testing.T) {
+	cases := map[string]string{
+		"variants": `
+        variants:
+          - mediaType: application/json
+            responseBody: '{}'
+          - mediaType: text/plain
+            responseBody: plain`,
+		"header match": `
+        responseBody: default
+        matches:
+          - when:
+              headers:
+                X-Tenant: acme
+            responseBody: acme`,
+		"body match": `
+        responseBody: default
+        matches:
+          - when:
+              body:
+                user.tier: gold
+            responseBody: gold`,
+		"scenario": `
+        scenario:
+          name: flow
+          states:
+            - state: start
+              responseBody: started`,
+		"echo": `
+        echo: true`,
+		"reflect": `
+        responseBody: '{"id": 0}'
+        reflect:
+          id: $.id`,
+		"script": `
+        script: 'nil'`,
+		"exec": `
+        type: exec
+        command: [cat]`,
+		"template file reading headers": `
+        template: true
+        responseFile: tenant.json`,
+		"inline template reading body": `
+        template: true
+        responseBody: '{"name": "{{$.Body.name}}"}'`,
+		"template calling a partial that reads body": `
+        template: true
+        responseBody: '{{template "user" .}}'`,
+	}
+	for name, endpoint := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := writeTestFiles(t, map[string]string{
+				"tenant.json":        "{\"tenant\": \"{{index .Headers `X-Tenant`}}\"}",
+				"partials/user.tmpl": `{{with .Body}}{{.name}}{{end}}`,
+				"config.yaml": `
+server:
+  templateDir: partials
+scenarios:
+  - name: flow
+    initialState: start
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /cached
+        method: GET
+        cacheTTL: 1m` + endpoint + "\n"})
+			_, err := loadConfig(filepath.Join(dir, "config.yaml"), loadOptions{AllowExec: true})
+			if err == nil || !strings.Contains(err.Error(), "cacheTTL") {
+				t.Fatalf("err = %v, want cacheTTL rejection", err)
+			}
+		})
+	}
+}
+
+// 查询参数是请求 URI 的一部分，按查询参数匹配的端点可以缓存
+func TestCacheTTLWithQueryMatch(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /cached
+        method: GET
+        cacheTTL: 1m
+        responseBody: default
+        matches:
+          - when:
+              query:
+                tier: gold
+            responseBody: gold
+`}, routerOptions{})
+	expectBody(t, s.get("/cached?tier=gold"), "gold")
+	expectBody(t, s.get("/cached"), "default")
+	w := s.get("/cached?tier=gold")
+	expectBody(t, w, "gold")
+	if got := w.Header().Get("X-Cache"); got != "HIT" {
+		t.Fatalf("X-Cache = %q, want HIT", got)
+	}
+}
+
+// 响应文件变化时只清空引用它的端点缓存，不需要重载配置
+func TestCacheInvalidatedByFile(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /a
+        method: GET
+        cacheTTL: 1h
+        responseFile: a.json
+      - path: /b
+        method: GET
+        cacheTTL: 1h
+        responseFile: b.json
+`,
+		"a.json": `{"v":1}`,
+		"b.json": `{"v":1}`,
+	}, routerOptions{})
+	expectBody(t, s.get("/a"), `{"v":1}`)
+	expectBody(t, s.get("/b"), `{"v":1}`)
+	s.write("a.json", `{"v":2}`)
+	s.write("b.json", `{"v":2}`)
+	if w := s.get("/a"); w.Header().Get("X-Cache") != "HIT" || w.Body.String() != `{"v":1}` {
+		t.Fatalf("before invalidation got %q (X-Cache %q), want the cached body", w.Body.String(), w.Header().Get("X-Cache"))
+	}
+
+	caches := s.handler.activeConfig().caches
+	if n := caches.invalidate(filepath.Join(s.dir, "a.json")); n != 1 {
+		t.Fatalf("invalidate cleared %d caches, want 1", n)
+	}
+	w := s.get("/a")
+	expectBody(t, w, `{"v":2}`)
+	if got := w.Header().Get("X-Cache"); got != "MISS" {
+		t.Fatalf("X-Cache = %q after invalidation, want MISS", got)
+	}
+	// 只清空被通知变化的文件对应的缓存，其他端点仍命中缓存
+	expectBody(t, s.get("/b"), `{"v":1}`)
+}
+
+// 只读取路径参数和查询参数的模板由请求 URI 决定，可以缓存
+func TestCacheTTLWithURITemplate(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /users/:id
+        method: GET
+        cacheTTL: 1m
+        template: true
+        responseBody: '{"id": "{{.Params.id}}", "q": "{{.Query.q}}"}'
+`}, routerOptions{})
+	expectBody(t, s.get("/users/1?q=a"), `{"id": "1", "q": "a"}`)
+	w := s.get("/users/1?q=a")
+	expectBody(t, w, `{"id": "1", "q": "a"}`)
+	if got := w.Header().Get("X-Cache"); got != "HIT" {
+		t.Fatalf("X-Cache = %q, want HIT", got)
+	}
+	expectBody(t, s.get("/users/2?q=a"), `{"id": "2", "q": "a"}`)
+}
+
+// timeout 在处理函数返回后恢复原来的请求，未超时的响应照常缓存；超时的响应不缓存
+func TestCacheTTLWithTimeout(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /fast
+        method: GET
+        cacheTTL: 1m
+        timeout: 1s
+        responseBody: fast
+      - path: /slow
+        method: GET
+        cacheTTL: 1m
+        timeout: 20ms
+        delay: 200ms
+        responseBody: slow
+`}, routerOptions{})
+	expectBody(t, s.get("/fast"), "fast")
+	if got := s.get("/fast").Header().Get("X-Cache"); got != "HIT" {
+		t.Fatalf("X-Cache = %q on the second request, want HIT", got)
+	}
+	for i := 0; i < 2; i++ {
+		w := s.get("/slow")
+		expectStatus(t, w, 504)
+		if got := w.Header().Get("X-Cache"); got == "HIT" {
+			t.Fatalf("timed out response was served from cache")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const defaultOverLimitStatus = 429
+
+// callCounterRegistry 按 routeKey（"METHOD path"，带 host 或 port 时附加在后）保存 callLimit 端点的累计调用次数。
+// 与 sequences 一样独立于路由引擎，重载配置后不会被重置，只能通过管理接口重置
+type callCounterRegistry struct {
+	mu     sync.Mutex
+	counts map[string]*atomic.Int64
+}
+
+var callCounters = &callCounterRegistry{counts: make(map[string]*atomic.Int64)}
+
+func (r *callCounterRegistry) get(key string) *atomic.Int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, ok := r.counts[key]
+	if !ok {
+		n = new(atomic.Int64)
+		r.counts[key] = n
+	}
+	return n
+}
+
+// reset 将与 method/path 匹配的计数清零，参数为空表示不限制，返回重置的数量
+func (r *callCounterRegistry) reset(method, path string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for key, n := range r.counts {
+		if !routeKeyMatches(key, method, path) {
+			continue
+		}
+		n.Store(0)
+		count++
+	}
+	return count
+}
+
+func (r *callCounterRegistry) snapshot() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]int64, len(r.counts))
+	for key, n := range r.counts {
+		out[key] = n.Load()
+	}
+	return out
+}
+
+// restore 与 sequenceRegistry.restore 相同，只修改已有的计数器，快照中没有的计数清零
+func (r *callCounterRegistry) restore(counts map[string]int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, n := range r.counts {
+		n.Store(counts[key])
+	}
+	for key, v := range counts {
+		if _, ok := r.counts[key]; !ok {
+			n := new(atomic.Int64)
+			n.Store(v)
+			r.counts[key] = n
+		}
+	}
+}
+
+// callLimiter 在累计调用超过 limit 次后返回 overLimit 响应，用于模拟配额耗尽。
+// 与 rateLimit 不同，计数不会随时间恢复
+type callLimiter struct {
+	count     *atomic.Int64
+	limit     int64
+	overLimit mockResponse
+}
+
+func newCallLimiter(key string, endpoint Endpoint) *callLimiter {
+	if endpoint.CallLimit <= 0 {
+		return nil
+	}
+	status := endpoint.OverLimitStatus
+	if status == 0 {
+		status = defaultOverLimitStatus
+	}
+	body := endpoint.OverLimitBody
+	if body == "" && endpoint.OverLimitFile == "" {
+		body = `{"error":"call limit exceeded"}`
+	}
+	return &callLimiter{
+		count: callCounters.get(key),
+		limit: int64(endpoint.CallLimit),
+		overLimit: mockResponse{
+			file:       endpoint.OverLimitFile,
+			body:       []byte(body),
+			statusCode: status,
+			rule:       "callLimit",
+		},
+	}
+}
+
+// exceeded 记录一次调用，并返回本次调用是否已超过上限
+func (l *callLimiter) exceeded() bool {
+	return l.count.Add(1) > l.limit
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// capturedExchange 是写入 recordDir 的一次请求/响应记录
+type capturedExchange struct {
+	Time     time.Time        `json:"time"`
+	Request  capturedRequest  `json:"request"`
+	Response capturedResponse `json:"response"`
+}
+
+type capturedRequest struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+	// BodyTruncated 表示请求体超过 captureBodyMax，只记录了前面的部分
+	BodyTruncated bool `json:"bodyTruncated,omitempty"`
+}
+
+type capturedResponse struct {
+	Status        int         `json:"status"`
+	Headers       http.Header `json:"headers"`
+	Body          string      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+}
+
+const (
+	captureQueueSize = 1024
+	// captureBodyMax 是每条记录中请求体和响应体各自保留的最大字节数，避免大请求占满内存和磁盘
+	captureBodyMax = 64 << 10
+)
+
+// captureWriter 在后台协程中写文件，请求处理只负责入队，不会因磁盘 IO 阻塞响应
+type captureWriter struct {
+	dir   string
+	queue chan capturedExchange
+}
+
+var (
+	captureWritersMu sync.Mutex
+	captureWriters   = make(map[string]*captureWriter)
+)
+
+// getCaptureWriter 按目录复用写入协程，重载配置重建路由时不会重复启动
+func getCaptureWriter(dir string) (*captureWriter, error) {
+	captureWritersMu.Lock()
+	defer captureWritersMu.Unlock()
+	if w, ok := captureWriters[dir]; ok {
+		return w, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	w := &captureWriter{dir: dir, queue: make(chan capturedExchange, captureQueueSize)}
+	go w.run()
+	captureWriters[dir] = w
+	return w, nil
+}
+
+func (w *captureWriter) run() {
+	for exchange := range w.queue {
+		data, err := json.MarshalIndent(exchange, "", "  ")
+		if err != nil {
+			log.Printf("Failed to encode captured request: %v", err)
+			continue
+		}
+		name := fmt.Sprintf("%s-%s-%s.json",
+			exchange.Time.Format("20060102T150405.000000000"),
+			exchange.Request.Method,
+			strings.Trim(unsafeFileChars.ReplaceAllString(exchange.Request.Path, "_"), "_"))
+		if err := os.WriteFile(filepath.Join(w.dir, name), data, 0o644); err != nil {
+			log.Printf("Failed to write captured request: %v", err)
+		}
+	}
+}
+
+func (w *captureWriter) enqueue(exchange capturedExchange) {
+	select {
+	case w.queue <- exchange:
+	default:
+		log.Printf("Capture queue full, dropping record of %s %s", exchange.Request.Method, exchange.Request.Path)
+	}
+}
+
+// teeWriter 在写出响应的同时保留一份响应体副本。limit 大于 0 时副本最多保留 limit 字节，
+// 超出的部分照常写给客户端，truncated 记录是否发生截断
+type teeWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *teeWriter) keep(data []byte) {
+	if w.limit > 0 && w.body.Len()+len(data) > w.limit {
+		data = data[:w.limit-w.body.Len()]
+		w.truncated = true
+	}
+	w.body.Write(data)
+}
+
+func (w *teeWriter) Write(data []byte) (int, error) {
+	w.keep(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *teeWriter) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// captureMiddleware 将每个请求和最终响应记录到 recordDir，includeBodies 控制是否记录请求体和响应体
+func captureMiddleware(w *captureWriter, includeBodies bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		exchange := capturedExchange{
+			Time: time.Now(),
+			Request: capturedRequest{
+				Method:  c.Request.Method,
+				Path:    c.Request.URL.Path,
+				Query:   c.Request.URL.RawQuery,
+				Headers: c.Request.Header.Clone(),
+			},
+		}
+		if includeBodies {
+			body, _ := readRequestBody(c)
+			if len(body) > captureBodyMax {
+				body = body[:captureBodyMax]
+				exchange.Request.BodyTruncated = true
+			}
+			exchange.Request.Body = string(body)
+		}
+
+		tee := &teeWriter{ResponseWriter: c.Writer, limit: captureBodyMax}
+		if includeBodies {
+			c.Writer = tee
+		}
+		c.Next()
+		if includeBodies {
+			c.Writer = tee.ResponseWriter
+			exchange.Response.Body = tee.body.String()
+			exchange.Response.BodyTruncated = tee.truncated
+		}
+
+		exchange.Response.Status = c.Writer.Status()
+		exchange.Response.Headers = c.Writer.Header().Clone()
+		w.enqueue(exchange)
+	}
+}
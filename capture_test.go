@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureTruncatesLargeBodies(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": `
+server:
+  recordDir: @DIR@/recorded
+services:
+  - name: svc
+    basePath: /
+    endpoints:
+      - path: /echo
+        method: POST
+        echo: true
+`}, routerOptions{})
+	size := captureBodyMax + 1000
+	w := s.do(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("x", size)))
+	expectStatus(t, w, http.StatusOK)
+	if w.Body.Len() != size {
+		t.Fatalf("client got %d bytes, want the full %d", w.Body.Len(), size)
+	}
+
+	// 记录由后台协程写入
+	var files []string
+	for deadline := time.Now().Add(2 * time.Second); len(files) == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		files, _ = filepath.Glob(filepath.Join(s.dir, "recorded", "*.json"))
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d recorded files, want 1", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exchange capturedExchange
+	if err := json.Unmarshal(data, &exchange); err != nil {
+		t.Fatal(err)
+	}
+	if len(exchange.Request.Body) != captureBodyMax || !exchange.Request.BodyTruncated {
+		t.Errorf("request body: %d bytes, truncated %v; want %d bytes, truncated", len(exchange.Request.Body), exchange.Request.BodyTruncated, captureBodyMax)
+	}
+	if len(exchange.Response.Body) != captureBodyMax || !exchange.Response.BodyTruncated {
+		t.Errorf("response body: %d bytes, truncated %v; want %d bytes, truncated", len(exchange.Response.Body), exchange.Response.BodyTruncated, captureBodyMax)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runCheck 实现 -check：加载并校验配置、尝试构建路由，但不监听端口。
+// 校验通过返回 0，否则打印错误并返回 1
+func runCheck(configPath string, opts loadOptions) int {
+	gin.SetMode(gin.ReleaseMode)
+	opts.StrictJSON = true
+
+	config, err := loadConfig(configPath, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config check failed: %v\n", err)
+		return 1
+	}
+	// 只检查路由能否注册，不创建 recordDir 等运行时资源
+	routerConfig := *config
+	routerConfig.Server.RecordDir = ""
+	if _, err := setupRouter(&routerConfig, routerOptions{}); err != nil {
+		fmt.Fprintf(os.Stderr, "Config check failed: %v\n", err)
+		return 1
+	}
+
+	endpoints := 0
+	for _, service := range config.Services {
+		endpoints += len(service.Endpoints)
+	}
+	fmt.Printf("Config OK: %d services, %d endpoints, %d response files\n", len(config.Services), endpoints, len(responseFiles(config)))
+	return 0
+}
@@ -0,0 +1,23 @@
+package main
+
+import "github.com/gin-gonic/gin"
+
+// ResponseCookie 是端点响应时设置的 cookie
+type ResponseCookie struct {
+	Name     string `yaml:"name" json:"name" toml:"name"`
+	Value    string `yaml:"value" json:"value" toml:"value"`
+	Path     string `yaml:"path" json:"path" toml:"path"`
+	MaxAge   int    `yaml:"maxAge" json:"maxAge" toml:"maxAge"`
+	HttpOnly bool   `yaml:"httpOnly" json:"httpOnly" toml:"httpOnly"`
+	Secure   bool   `yaml:"secure" json:"secure" toml:"secure"`
+}
+
+func setCookies(c *gin.Context, cookies []ResponseCookie) {
+	for _, cookie := range cookies {
+		path := cookie.Path
+		if path == "" {
+			path = "/"
+		}
+		c.SetCookie(cookie.Name, cookie.Value, cookie.MaxAge, path, "", cookie.Secure, cookie.HttpOnly)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig 可以配置在 server 下作为全局默认值，也可以配置在单个 service 下覆盖全局值
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allowOrigins" json:"allowOrigins" toml:"allowOrigins"`
+	AllowMethods []string `yaml:"allowMethods" json:"allowMethods" toml:"allowMethods"`
+	AllowHeaders []string `yaml:"allowHeaders" json:"allowHeaders" toml:"allowHeaders"`
+	// AllowCredentials 为 true 时返回 Access-Control-Allow-Credentials: true，
+	// 浏览器要求此时 Allow-Origin 必须是具体的来源
+	AllowCredentials bool `yaml:"allowCredentials" json:"allowCredentials" toml:"allowCredentials"`
+	// MaxAge 是浏览器缓存预检结果的时间，以秒为单位写入 Access-Control-Max-Age
+	MaxAge Duration `yaml:"maxAge" json:"maxAge" toml:"maxAge"`
+}
+
+func (cfg *CORSConfig) validate() error {
+	if cfg.AllowCredentials {
+		for _, allowed := range cfg.AllowOrigins {
+			if allowed == "*" {
+				return fmt.Errorf(`cors allowCredentials cannot be combined with allowOrigins "*", browsers reject credentialed responses for a wildcard origin; list the allowed origins explicitly`)
+			}
+		}
+	}
+	if cfg.MaxAge < 0 {
+		return fmt.Errorf("cors maxAge must not be negative")
+	}
+	return nil
+}
+
+var defaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"}
+
+func (cfg *CORSConfig) allowOrigin(origin string) (string, bool) {
+	for _, allowed := range cfg.AllowOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+func corsMiddleware(cfg *CORSConfig) gin.HandlerFunc {
+	methods := cfg.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	maxAge := ""
+	if cfg.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(time.Duration(cfg.MaxAge) / time.Second))
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			return
+		}
+		allowed, ok := cfg.allowOrigin(origin)
+		if !ok {
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", allowed)
+		if allowed != "*" {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				c.Header("Access-Control-Allow-Headers", allowHeaders)
+			} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				c.Header("Access-Control-Allow-Headers", requested)
+			}
+			if maxAge != "" {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+		}
+	}
+}
+
+// preflightHandler 自动应答未被用户显式配置的 OPTIONS 预检请求
+func preflightHandler(c *gin.Context) {
+	c.AbortWithStatus(http.StatusNoContent)
+}
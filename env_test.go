@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const envConfig = `
+services:
+  - name: api
+    basePath: ${MOCK_BASE}
+    endpoints:
+      - path: /user
+        method: GET
+        responseFile: ${MOCK_DATA}/user.json
+      - path: /greeting
+        method: GET
+        responseBody: "hello $MOCK_NAME, that costs $$5"
+`
+
+func TestEnvSubstitution(t *testing.T) {
+	t.Setenv("MOCK_BASE", "/api")
+	t.Setenv("MOCK_DATA", "fixtures")
+	t.Setenv("MOCK_NAME", "env")
+	s := newTestServer(t, map[string]string{
+		"config.yaml":        envConfig,
+		"fixtures/user.json": `{"name":"env"}`,
+	}, routerOptions{})
+	expectBody(t, s.get("/api/user"), `{"name":"env"}`)
+	expectBody(t, s.get("/api/greeting"), "hello env, that costs $5")
+}
+
+func TestEnvSubstitutionStrict(t *testing.T) {
+	t.Setenv("MOCK_BASE", "/api")
+	t.Setenv("MOCK_DATA", "fixtures")
+	dir := writeTestFiles(t, map[string]string{
+		"config.yaml":        envConfig,
+		"fixtures/user.json": `{}`,
+	})
+	path := filepath.Join(dir, "config.yaml")
+	if _, err := loadConfig(path, loadOptions{StrictEnv: true}); err == nil || !strings.Contains(err.Error(), "MOCK_NAME") {
+		t.Fatalf("strict load err = %v, want undefined MOCK_NAME", err)
+	}
+	// 非严格模式下未定义的变量展开为空字符串
+	if _, err := loadConfig(path, loadOptions{}); err != nil {
+		t.Fatalf("non-strict load: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	endpointTypeExec   = "exec"
+	defaultExecTimeout = 10 * time.Second
+	defaultExecWorkers = 4
+)
+
+// execRequest 是以 JSON 形式写入命令 stdin 的请求内容
+type execRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   map[string][]string `json:"query"`
+	Headers map[string][]string `json:"headers"`
+	Params  map[string]string   `json:"params"`
+	Body    string              `json:"body"`
+}
+
+// validateExec 在加载时检查命令能否找到，exitStatus 中的状态码必须合法
+func validateExec(endpoint Endpoint) error {
+	if len(endpoint.Command) == 0 {
+		return fmt.Errorf("exec endpoint has no command")
+	}
+	if _, err := exec.LookPath(endpoint.Command[0]); err != nil {
+		return fmt.Errorf("exec command: %v", err)
+	}
+	// 退出码以字符串作为键，TOML 不支持整数键
+	for code, status := range endpoint.ExitStatus {
+		if n, err := strconv.Atoi(code); err != nil || n < 0 || n > 255 {
+			return fmt.Errorf("exitStatus key %q is not an exit code (0-255)", code)
+		}
+		if status < 100 || status > 999 {
+			return fmt.Errorf("exitStatus for exit code %s has invalid status %d", code, status)
+		}
+	}
+	return nil
+}
+
+// checkExecAllowed 拒绝在没有 -allow-exec 时加载 exec 端点，避免配置文件悄悄获得执行命令的能力
+func checkExecAllowed(config *Config, allowed bool) error {
+	if allowed {
+		return nil
+	}
+	for _, service := range config.Services {
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Type == endpointTypeExec {
+				return fmt.Errorf("endpoint %s %s in service %s has type exec, which requires the -allow-exec flag", endpoint.Method, service.BasePath+endpoint.Path, service.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// newExecHandler 运行配置的命令（不经过 shell），请求以 JSON 写入 stdin，stdout 作为响应体。
+// 退出码 0 返回 statusCode，非 0 按 exitStatus 映射，没有映射时返回 500。
+// 命令在 pool 限定的并发数内执行：等待空闲 worker 超过 timeout（默认 defaultExecTimeout）时返回 503，
+// 取得 worker 后命令运行超过 timeout 会被终止并返回 504，配置了 timeoutBody 时以它作为响应体
+func newExecHandler(hc handlerContext, endpoint Endpoint) gin.HandlerFunc {
+	timeout := time.Duration(endpoint.Timeout)
+	if timeout <= 0 {
+		timeout = defaultExecTimeout
+	}
+	statusCode := endpoint.StatusCode
+	if statusCode == 0 {
+		statusCode = 200
+	}
+	contentType := "application/json"
+	for k, v := range endpoint.Headers {
+		if http.CanonicalHeaderKey(k) == "Content-Type" {
+			contentType = v
+		}
+	}
+	pool := hc.opts.ExecPool
+	return func(c *gin.Context) {
+		body, err := readRequestBody(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req := execRequest{
+			Method:  c.Request.Method,
+			Path:    c.Request.URL.Path,
+			Query:   c.Request.URL.Query(),
+			Headers: c.Request.Header,
+			Params:  make(map[string]string),
+			Body:    string(body),
+		}
+		for _, p := range c.Params {
+			req.Params[p.Key] = p.Value
+		}
+		input, err := json.Marshal(req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 排队时间单独计时，不占用命令自己的 timeout
+		if pool != nil {
+			queueCtx, queueCancel := context.WithTimeout(c.Request.Context(), timeout)
+			select {
+			case pool <- struct{}{}:
+				queueCancel()
+				defer func() { <-pool }()
+			case <-queueCtx.Done():
+				queueCancel()
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no exec worker available"})
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, endpoint.Command[0], endpoint.Command[1:]...)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+
+		status := statusCode
+		if err != nil {
+			var exitErr *exec.ExitError
+			switch {
+			case errors.Is(ctx.Err(), context.DeadlineExceeded):
+				log.Printf("Exec %s timed out after %v", endpoint.Command[0], timeout)
+				if endpoint.TimeoutBody != "" {
+					c.Data(http.StatusGatewayTimeout, "application/json", []byte(endpoint.TimeoutBody))
+					return
+				}
+				c.JSON(http.StatusGatewayTimeout, gin.H{"error": "exec command timed out"})
+				return
+			case errors.As(err, &exitErr):
+				mapped, ok := endpoint.ExitStatus[strconv.Itoa(exitErr.ExitCode())]
+				if !ok {
+					mapped = http.StatusInternalServerError
+				}
+				status = mapped
+				if msg := strings.TrimSpace(stderr.String()); msg != "" {
+					log.Printf("Exec %s exited with code %d: %s", endpoint.Command[0], exitErr.ExitCode(), msg)
+				}
+			default:
+				log.Printf("Exec %s failed: %v", endpoint.Command[0], err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		for k, v := range endpoint.Headers {
+			c.Header(k, v)
+		}
+		c.Data(status, contentType, stdout.Bytes())
+	}
+}
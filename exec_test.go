@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+const execConfig = `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /sleep
+        method: GET
+        type: exec
+        command: [sleep, "0.2"]
+        timeout: 300ms
+      - path: /hang
+        method: GET
+        type: exec
+        command: [sleep, "5"]
+        timeout: 100ms
+`
+
+func newExecServer(t *testing.T, workers int) *testServer {
+	return newTestServerWithLoad(t, map[string]string{"config.yaml": execConfig},
+		routerOptions{ExecPool: make(chan struct{}, workers)}, loadOptions{AllowExec: true})
+}
+
+// concurrentGets 并发请求 target，返回各请求的状态码
+func concurrentGets(s *testServer, target string, n int) []int {
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			s.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+	return codes
+}
+
+// 排队等待 worker 的时间不计入命令的 timeout：第二个请求等待约 200ms 后仍有完整的 300ms 运行
+func TestExecQueueTimeNotCountedAgainstTimeout(t *testing.T) {
+	s := newExecServer(t, 1)
+	for _, code := range concurrentGets(s, "/sleep", 2) {
+		if code != http.StatusOK {
+			t.Fatalf("statuses = %v, want both 200", code)
+		}
+	}
+}
+
+// 命令运行超时返回 504，等待 worker 超时返回 503
+func TestExecTimeoutStatuses(t *testing.T) {
+	s := newExecServer(t, 1)
+	expectStatus(t, s.get("/hang"), http.StatusGatewayTimeout)
+
+	counts := make(map[int]int)
+	for _, code := range concurrentGets(s, "/hang", 3) {
+		counts[code]++
+	}
+	// 第一个请求取得 worker 后运行超时；其余请求排队 100ms 后 worker 仍被占用
+	if counts[http.StatusGatewayTimeout] < 1 || counts[http.StatusServiceUnavailable] < 1 || counts[http.StatusGatewayTimeout]+counts[http.StatusServiceUnavailable] != 3 {
+		t.Fatalf("statuses = %v, want 504 for the running command and 503 for queued ones", counts)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FallbackConfig 是响应文件无法读取时返回的内联响应，便于在编写 fixture 期间继续使用 mock
+type FallbackConfig struct {
+	StatusCode   int    `yaml:"statusCode" json:"statusCode" toml:"statusCode"`
+	ResponseBody string `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+}
+
+// fileError 处理读取响应文件失败：配置了 fallback 时记录错误并返回 fallback，
+// 否则照旧返回 500 和错误信息。错误原因会附加到访问日志中，并计入 mock_response_file_errors_total
+func (hc handlerContext) fileError(c *gin.Context, file string, err error) {
+	c.Error(fmt.Errorf("read response file %s: %v", file, err))
+	if hc.opts.Metrics != nil {
+		hc.opts.Metrics.fileErrors.WithLabelValues(file).Inc()
+	}
+	fb := hc.config.Fallback
+	if fb == nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Failed to read response file %s, serving fallback: %v", file, err)
+	statusCode := fb.StatusCode
+	if statusCode == 0 {
+		statusCode = 200
+	}
+	c.Data(statusCode, "application/json", []byte(fb.ResponseBody))
+}
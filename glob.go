@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	fileSelectRandom     = "random"
+	fileSelectRoundRobin = "roundRobin"
+)
+
+func isGlobPattern(file string) bool {
+	return strings.ContainsAny(file, "*?[")
+}
+
+// expandGlobs 展开端点 responseFile 中的通配符，匹配结果按文件名排序。
+// 加载和每次重载配置时都会重新展开，没有任何匹配时返回错误
+func expandGlobs(config *Config) error {
+	for i := range config.Services {
+		service := &config.Services[i]
+		for j := range service.Endpoints {
+			endpoint := &service.Endpoints[j]
+			if !isGlobPattern(endpoint.ResponseFile) {
+				continue
+			}
+			fullPath := service.BasePath + endpoint.Path
+			if endpoint.Type != "" {
+				return fmt.Errorf("endpoint %s %s in service %s: glob responseFile is not supported for type %s", endpoint.Method, fullPath, service.Name, endpoint.Type)
+			}
+			matches, err := filepath.Glob(endpoint.ResponseFile)
+			if err != nil {
+				return fmt.Errorf("endpoint %s %s in service %s has invalid responseFile pattern %q: %v", endpoint.Method, fullPath, service.Name, endpoint.ResponseFile, err)
+			}
+			if len(matches) == 0 {
+				return fmt.Errorf("endpoint %s %s in service %s: responseFile pattern %q matches no files", endpoint.Method, fullPath, service.Name, endpoint.ResponseFile)
+			}
+			sort.Strings(matches)
+			endpoint.globFiles = matches
+		}
+	}
+	return nil
+}
+
+// filePicker 每次请求从通配符匹配到的文件中选一个，随机或轮流
+type filePicker struct {
+	files      []string
+	roundRobin bool
+	next       atomic.Uint64
+}
+
+func newFilePicker(endpoint Endpoint) *filePicker {
+	if len(endpoint.globFiles) == 0 {
+		return nil
+	}
+	return &filePicker{files: endpoint.globFiles, roundRobin: endpoint.FileSelect == fileSelectRoundRobin}
+}
+
+// pick 用选中的文件替换默认响应的 responseFile
+func (p *filePicker) pick(resp mockResponse) mockResponse {
+	var i int
+	if p.roundRobin {
+		i = int((p.next.Add(1) - 1) % uint64(len(p.files)))
+	} else {
+		i = int(rng.Int63n(int64(len(p.files))))
+	}
+	resp.file = p.files[i]
+	resp.rule = "responseFile " + filepath.Base(p.files[i])
+	return resp
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const endpointTypeGRPCWeb = "grpc-web"
+
+const (
+	grpcWebDataFrame    = 0x00
+	grpcWebTrailerFrame = 0x80
+	// grpcMaxStatus 是最大的标准 gRPC 状态码（UNAUTHENTICATED）
+	grpcMaxStatus = 16
+)
+
+// validateGRPCWeb 在加载时检查 grpcStatus 是否为标准 gRPC 状态码
+func validateGRPCWeb(endpoint Endpoint) error {
+	if endpoint.GRPCStatus < 0 || endpoint.GRPCStatus > grpcMaxStatus {
+		return fmt.Errorf("grpcStatus %d is not a valid gRPC status code (0-%d)", endpoint.GRPCStatus, grpcMaxStatus)
+	}
+	return nil
+}
+
+// grpcWebFrame 按 grpc-web 格式编码一帧：1 字节标志 + 4 字节大端长度 + 内容
+func grpcWebFrame(flag byte, payload []byte) []byte {
+	frame := make([]byte, 5+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+	return frame
+}
+
+// grpcEncodeMessage 按 gRPC 规范对 grpc-message 做百分号编码
+func grpcEncodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		ch := msg[i]
+		if ch >= 0x20 && ch <= 0x7e && ch != '%' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// newGRPCWebHandler 把响应文件内容作为一条已编码的 protobuf 消息返回，随后是携带
+// grpc-status 和 grpc-message 的 trailer 帧。请求使用 grpc-web-text 时整体按 base64 输出
+func newGRPCWebHandler(hc handlerContext, endpoint Endpoint) gin.HandlerFunc {
+	trailer := "grpc-status: " + strconv.Itoa(endpoint.GRPCStatus) + "\r\n"
+	if endpoint.GRPCMessage != "" {
+		trailer += "grpc-message: " + grpcEncodeMessage(endpoint.GRPCMessage) + "\r\n"
+	}
+	return func(c *gin.Context) {
+		var message []byte
+		switch {
+		case endpoint.ResponseFile != "":
+			data, err := readJSONFile(endpoint.ResponseFile)
+			if err != nil {
+				hc.fileError(c, endpoint.ResponseFile, err)
+				return
+			}
+			message = data
+		case endpoint.ResponseBodyBase64 != "":
+			message, _ = base64.StdEncoding.DecodeString(endpoint.ResponseBodyBase64)
+		default:
+			message = []byte(endpoint.ResponseBody)
+		}
+
+		var body []byte
+		// 没有消息体的错误响应只发送 trailer 帧
+		if len(message) > 0 || endpoint.GRPCStatus == 0 {
+			body = append(body, grpcWebFrame(grpcWebDataFrame, message)...)
+		}
+		body = append(body, grpcWebFrame(grpcWebTrailerFrame, []byte(trailer))...)
+
+		contentType := "application/grpc-web+proto"
+		if strings.HasPrefix(c.GetHeader("Content-Type"), "application/grpc-web-text") {
+			contentType = "application/grpc-web-text+proto"
+			body = []byte(base64.StdEncoding.EncodeToString(body))
+		}
+		for k, v := range endpoint.Headers {
+			c.Header(k, v)
+		}
+		c.Data(200, contentType, body)
+	}
+}
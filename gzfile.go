@@ -0,0 +1,45 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipMode 控制 .gz 响应文件的返回方式：
+//   - auto（默认）：客户端接受 gzip 时原样返回并带上 Content-Encoding: gzip，否则解压后返回
+//   - decompress：总是解压后返回
+//   - raw：当作普通的二进制文件返回，不做任何处理
+const (
+	gzipModeAuto       = "auto"
+	gzipModeDecompress = "decompress"
+	gzipModeRaw        = "raw"
+)
+
+func isGzipFile(file string) bool {
+	return strings.HasSuffix(strings.ToLower(file), ".gz")
+}
+
+// fixtureName 返回用于推断内容类型的文件名，例如 users.json.gz 按 users.json 处理
+func fixtureName(file string) string {
+	if isGzipFile(file) {
+		return file[:len(file)-len(".gz")]
+	}
+	return file
+}
+
+// gunzipFile 读取并解压 .gz 文件
+func gunzipFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
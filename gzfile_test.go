@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestGzipFixture(t *testing.T) {
+	const users = `{"users":["a","b"]}`
+	compressed := gzipBytes(t, users)
+	s := newTestServer(t, map[string]string{
+		"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /auto
+        method: GET
+        responseFile: users.json.gz
+      - path: /decompress
+        method: GET
+        responseFile: users.json.gz
+        gzipMode: decompress
+      - path: /raw
+        method: GET
+        responseFile: users.json.gz
+        gzipMode: raw
+`,
+		"users.json.gz": compressed,
+	}, routerOptions{})
+
+	// 接受 gzip 的客户端直接收到压缩数据，内容类型按解压后的文件名推断
+	w := s.get("/auto", "Accept-Encoding", "gzip, deflate")
+	expectStatus(t, w, 200)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Body.String() != compressed {
+		t.Fatalf("gzip client got Content-Encoding %q and %d bytes, want the gzip file as is", w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	if !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+		t.Fatalf("Vary = %q, want Accept-Encoding", w.Header().Get("Vary"))
+	}
+
+	// 不接受 gzip 的客户端收到解压后的内容
+	w = s.get("/auto")
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("plain client got Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+	expectBody(t, w, users)
+
+	w = s.get("/decompress", "Accept-Encoding", "gzip")
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("decompress mode got Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+	expectBody(t, w, users)
+
+	w = s.get("/raw", "Accept-Encoding", "gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != compressed {
+		t.Fatalf("raw mode should serve the file bytes without Content-Encoding")
+	}
+}
+
+// 缓存 key 不含 Accept-Encoding，auto 模式的 .gz 文件不能与 cacheTTL 同时使用；decompress 模式可以
+func TestGzipFixtureCacheTTL(t *testing.T) {
+	files := map[string]string{"users.json.gz": gzipBytes(t, `{"users":[]}`)}
+	for mode, ok := range map[string]bool{"": false, gzipModeAuto: false, gzipModeDecompress: true, gzipModeRaw: true} {
+		files["config.yaml"] = `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /users
+        method: GET
+        cacheTTL: 1m
+        gzipMode: "` + mode + `"
+        responseFile: users.json.gz
+`
+		dir := writeTestFiles(t, files)
+		_, err := loadConfig(filepath.Join(dir, "config.yaml"), loadOptions{})
+		if ok && err != nil {
+			t.Fatalf("gzipMode %q: unexpected error %v", mode, err)
+		}
+		if !ok && (err == nil || !strings.Contains(err.Error(), "cacheTTL")) {
+			t.Fatalf("gzipMode %q: err = %v, want cacheTTL rejection", mode, err)
+		}
+	}
+
+	s := newTestServer(t, map[string]string{
+		"users.json.gz": files["users.json.gz"],
+		"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /users
+        method: GET
+        cacheTTL: 1m
+        gzipMode: decompress
+        responseFile: users.json.gz
+`}, routerOptions{})
+	expectBody(t, s.get("/users", "Accept-Encoding", "gzip"), `{"users":[]}`)
+	w := s.get("/users")
+	expectBody(t, w, `{"users":[]}`)
+	if w.Header().Get("X-Cache") != "HIT" || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("X-Cache %q, Content-Encoding %q; want a plain cache hit", w.Header().Get("X-Cache"), w.Header().Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// 与 -h2c 相同的方式包装 handler，客户端以 prior knowledge 直接用明文 HTTP/2 连接
+func TestH2CServesEndpoints(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /users/:id
+        method: GET
+        template: true
+        responseFile: user.json
+        headers:
+          X-Mock: "yes"
+`,
+		"user.json": `{"id":"{{.Params.id}}"}`,
+	}, routerOptions{})
+	srv := httptest.NewServer(h2c.NewHandler(s.handler, &http2.Server{}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get(srv.URL + "/users/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("proto = %s, want HTTP/2", resp.Proto)
+	}
+	if resp.StatusCode != 200 || string(body) != `{"id":"7"}` || resp.Header.Get("X-Mock") != "yes" {
+		t.Fatalf("got %d %q X-Mock=%q", resp.StatusCode, body, resp.Header.Get("X-Mock"))
+	}
+
+	// 普通 HTTP/1.1 客户端仍然可用
+	plain, err := srv.Client().Get(srv.URL + "/users/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Body.Close()
+	if plain.ProtoMajor != 1 || plain.StatusCode != 200 {
+		t.Fatalf("plain client got %s %d", plain.Proto, plain.StatusCode)
+	}
+}
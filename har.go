@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// harFile 是 HAR 文件中回放需要的部分
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int         `json:"status"`
+		Headers []harHeader `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// 回放时由服务端重新生成的响应头，不从录制内容中复制
+var harSkippedHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Date":              true,
+}
+
+// loadHAR 为 HAR 中的每个 method+path 生成一个端点。不带查询参数的记录按录制顺序组成 sequence；
+// 带查询参数的记录按 method+path+query 去重，每种查询生成一条按查询参数匹配的规则，
+// 参数多的规则排在前面。没有不带查询参数的记录时，未命中规则的请求返回该路径的第一条记录
+func loadHAR(filename string) (*Service, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid har file: %v", err)
+	}
+
+	service := &Service{Name: "har", source: filename}
+	index := make(map[string]int)
+	seenQueries := make(map[string]bool)
+	// 只有带查询参数的记录的端点，在没有不带查询参数的记录之前用第一条记录兜底
+	fallbackOnly := make(map[int]bool)
+	duplicates := 0
+	for i, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: invalid url %q: %v", i, entry.Request.URL, err)
+		}
+		body := entry.Response.Content.Text
+		if entry.Response.Content.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(body)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: invalid base64 content: %v", i, err)
+			}
+			body = string(decoded)
+		}
+		step := SequenceStep{StatusCode: entry.Response.Status, ResponseBody: body}
+
+		method := strings.ToUpper(entry.Request.Method)
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		key := method + " " + path
+		j, ok := index[key]
+		if !ok {
+			headers, cookies := harResponseHeaders(entry)
+			j = len(service.Endpoints)
+			index[key] = j
+			service.Endpoints = append(service.Endpoints, Endpoint{
+				Path:       path,
+				Method:     method,
+				Headers:    headers,
+				Cookies:    cookies,
+				allowEmpty: true,
+			})
+		}
+		endpoint := &service.Endpoints[j]
+
+		query := u.Query()
+		if len(query) == 0 {
+			if fallbackOnly[j] {
+				endpoint.Sequence = nil
+				delete(fallbackOnly, j)
+			}
+			endpoint.Sequence = append(endpoint.Sequence, step)
+			continue
+		}
+		queryKey := key + "?" + query.Encode()
+		if seenQueries[queryKey] {
+			duplicates++
+			continue
+		}
+		seenQueries[queryKey] = true
+		when := MatchCondition{Query: make(map[string]string)}
+		for name, values := range query {
+			when.Query[name] = values[0]
+		}
+		endpoint.Matches = append(endpoint.Matches, MatchRule{When: when, StatusCode: step.StatusCode, ResponseBody: step.ResponseBody})
+		if len(endpoint.Sequence) == 0 {
+			endpoint.Sequence = []SequenceStep{step}
+			fallbackOnly[j] = true
+		}
+	}
+	for i := range service.Endpoints {
+		rules := service.Endpoints[i].Matches
+		sort.SliceStable(rules, func(a, b int) bool { return len(rules[a].When.Query) > len(rules[b].When.Query) })
+	}
+
+	if duplicates > 0 {
+		log.Printf("Warning: %d har entries in %s repeat an earlier method, path and query, only the first recording is replayed", duplicates, filename)
+	}
+	log.Printf("Loaded %d endpoints from %d har entries in %s", len(service.Endpoints), len(har.Log.Entries), filename)
+	return service, nil
+}
+
+// harResponseHeaders 返回记录的响应头。重复的 Set-Cookie 转成 cookie 列表，
+// 其他重复的响应头按出现顺序以逗号合并
+func harResponseHeaders(entry harEntry) (map[string]string, []ResponseCookie) {
+	headers := make(map[string]string)
+	setCookies := http.Header{}
+	for _, h := range entry.Response.Headers {
+		name := http.CanonicalHeaderKey(h.Name)
+		switch {
+		case harSkippedHeaders[name]:
+		case name == "Set-Cookie":
+			setCookies.Add(name, h.Value)
+		case headers[name] != "":
+			headers[name] += ", " + h.Value
+		default:
+			headers[name] = h.Value
+		}
+	}
+	if _, ok := headers["Content-Type"]; !ok && entry.Response.Content.MimeType != "" {
+		headers["Content-Type"] = entry.Response.Content.MimeType
+	}
+
+	var cookies []ResponseCookie
+	for _, cookie := range (&http.Response{Header: setCookies}).Cookies() {
+		cookies = append(cookies, ResponseCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			MaxAge:   cookie.MaxAge,
+			HttpOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+		})
+	}
+	return headers, cookies
+}
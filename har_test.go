@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+)
+
+const harConfig = `
+services: []
+`
+
+const harRecording = `{"log": {"entries": [
+  {"request": {"method": "GET", "url": "http://api.test/items?page=2"},
+   "response": {"status": 200, "headers": [
+     {"name": "Set-Cookie", "value": "a=1; Path=/"},
+     {"name": "set-cookie", "value": "b=2; Path=/; HttpOnly"},
+     {"name": "Cache-Control", "value": "no-cache"},
+     {"name": "Cache-Control", "value": "no-store"}],
+    "content": {"mimeType": "application/json", "text": "{\"page\":2}"}}},
+  {"request": {"method": "GET", "url": "http://api.test/items"},
+   "response": {"status": 200, "headers": [], "content": {"mimeType": "application/json", "text": "{\"page\":1}"}}},
+  {"request": {"method": "GET", "url": "http://api.test/items?page=2&sort=name"},
+   "response": {"status": 200, "headers": [], "content": {"mimeType": "application/json", "text": "{\"page\":2,\"sort\":\"name\"}"}}},
+  {"request": {"method": "GET", "url": "http://api.test/items?page=2"},
+   "response": {"status": 500, "headers": [], "content": {"text": "duplicate"}}},
+  {"request": {"method": "GET", "url": "http://api.test/search?q=go"},
+   "response": {"status": 200, "headers": [], "content": {"mimeType": "text/plain", "text": "go results"}}},
+  {"request": {"method": "DELETE", "url": "http://api.test/items/1"},
+   "response": {"status": 204, "headers": [], "content": {}}}
+]}}`
+
+func newHARServer(t *testing.T) *testServer {
+	return newTestServerWithLoad(t, map[string]string{"config.yaml": harConfig, "rec.har": harRecording},
+		routerOptions{}, loadOptions{HARFile: "@DIR@/rec.har"})
+}
+
+// 同一路径的不同查询参数是不同的记录，按查询参数选择响应
+func TestHARReplaysByQuery(t *testing.T) {
+	s := newHARServer(t)
+	expectBody(t, s.get("/items"), `{"page":1}`)
+	expectBody(t, s.get("/items?page=2"), `{"page":2}`)
+	expectBody(t, s.get("/items?sort=name&page=2"), `{"page":2,"sort":"name"}`)
+	expectBody(t, s.get("/items?page=3"), `{"page":1}`)
+	// 只有带查询参数的记录时，其他查询返回该路径的第一条记录
+	expectBody(t, s.get("/search?q=rust"), "go results")
+	expectStatus(t, s.do("DELETE", "/items/1", nil), 204)
+}
+
+func TestHARKeepsRepeatedHeaders(t *testing.T) {
+	s := newHARServer(t)
+	w := s.get("/items?page=2")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 2 || cookies[0].Name != "a" || cookies[1].Name != "b" || !cookies[1].HttpOnly {
+		t.Fatalf("cookies = %v, want a and HttpOnly b", cookies)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-cache, no-store" {
+		t.Fatalf("Cache-Control = %q, want both recorded values", got)
+	}
+}
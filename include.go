@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// configLoader 加载配置文件及其 include 的文件。同一文件只加载一次，
+// 多个文件 include 同一份公共配置时不会产生重复路由
+type configLoader struct {
+	opts   loadOptions
+	loaded map[string]bool
+	stack  []string
+	// files 按加载顺序记录所有读取过的配置文件，用于文件监控
+	files []string
+}
+
+func newConfigLoader(opts loadOptions) *configLoader {
+	return &configLoader{opts: opts, loaded: make(map[string]bool)}
+}
+
+// load 解析 filename 并合并到 dst，随后按顺序加载 include 的文件。
+// include 的相对路径相对于包含它的文件所在目录
+func (l *configLoader) load(dst *Config, filename string) error {
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	for i, p := range l.stack {
+		if p == absPath {
+			cycle := append(append([]string{}, l.stack[i:]...), absPath)
+			return fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if l.loaded[absPath] {
+		return nil
+	}
+	l.loaded[absPath] = true
+	l.files = append(l.files, filename)
+
+	fileConfig, err := parseConfigFile(filename, l.opts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	mergeConfig(dst, fileConfig)
+
+	l.stack = append(l.stack, absPath)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+	for _, include := range fileConfig.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(filename), include)
+		}
+		if err := l.load(dst, include); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	jsonFormatPassthrough = "passthrough"
+	jsonFormatPretty      = "pretty"
+	jsonFormatMinify      = "minify"
+)
+
+func validateJSONFormat(mode string) error {
+	switch mode {
+	case jsonFormatPassthrough, jsonFormatPretty, jsonFormatMinify:
+		return nil
+	}
+	return fmt.Errorf("invalid -json %q, expected pretty, minify or passthrough", mode)
+}
+
+// formatJSON 按 -json 的设置重新排版 JSON 响应体，pretty 使用两个空格缩进，minify 去掉所有多余空白。
+// 不是合法 JSON 的内容原样返回
+func formatJSON(data []byte, mode string) []byte {
+	var buf bytes.Buffer
+	switch mode {
+	case jsonFormatPretty:
+		if err := json.Indent(&buf, bytes.TrimSpace(data), "", "  "); err != nil {
+			return data
+		}
+		buf.WriteByte('\n')
+	case jsonFormatMinify:
+		if err := json.Compact(&buf, data); err != nil {
+			return data
+		}
+	default:
+		return data
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+var jsonFormatFiles = map[string]string{
+	"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /user
+        method: GET
+        responseFile: user.json
+      - path: /broken
+        method: GET
+        responseFile: broken.json
+      - path: /text
+        method: GET
+        responseFile: note.txt
+`,
+	"user.json":   "{ \"name\": \"a\",\n    \"tags\": [1, 2] }",
+	"broken.json": "{ \"name\": ",
+	"note.txt":    "{ \"not\": \"json content type\" }",
+}
+
+func TestJSONFormatModes(t *testing.T) {
+	cases := []struct {
+		mode string
+		user string
+	}{
+		{jsonFormatPassthrough, "{ \"name\": \"a\",\n    \"tags\": [1, 2] }"},
+		{jsonFormatPretty, "{\n  \"name\": \"a\",\n  \"tags\": [\n    1,\n    2\n  ]\n}\n"},
+		{jsonFormatMinify, `{"name":"a","tags":[1,2]}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.mode, func(t *testing.T) {
+			s := newTestServer(t, jsonFormatFiles, routerOptions{JSONFormat: tc.mode})
+			if got := s.get("/user").Body.String(); got != tc.user {
+				t.Fatalf("body = %q, want %q", got, tc.user)
+			}
+			// 非法 JSON 和非 JSON 内容类型原样返回
+			if got := s.get("/broken").Body.String(); got != jsonFormatFiles["broken.json"] {
+				t.Fatalf("invalid JSON body = %q, want it untouched", got)
+			}
+			if got := s.get("/text").Body.String(); got != jsonFormatFiles["note.txt"] {
+				t.Fatalf("text body = %q, want it untouched", got)
+			}
+		})
+	}
+}
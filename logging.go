@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accessLogEntry 是 JSON 格式访问日志中的一条记录
+type accessLogEntry struct {
+	Time      string  `json:"time"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	ClientIP  string  `json:"client_ip"`
+	Size      int     `json:"size"`
+	RequestID string  `json:"request_id,omitempty"`
+	// RequestBody 只在端点配置了 logBody 时输出
+	RequestBody *string `json:"request_body,omitempty"`
+	// Error 是处理请求时记录的错误，例如读取响应文件失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+const (
+	logBodyKey        = "mock.logBody"
+	defaultLogBodyMax = 4096
+)
+
+// logBodyMiddleware 为配置了 logBody 的端点记录请求体，超过 maxBytes 的部分截断。
+// JSON 日志把请求体放进访问日志的同一行，文本日志单独输出一行
+func logBodyMiddleware(logFormat string, maxBytes int) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = defaultLogBodyMax
+	}
+	return func(c *gin.Context) {
+		body, err := readRequestBody(c)
+		if err != nil {
+			return
+		}
+		logged := string(body)
+		if len(body) > maxBytes {
+			logged = fmt.Sprintf("%s...(truncated, %d bytes total)", body[:maxBytes], len(body))
+		}
+		if logFormat == "json" {
+			c.Set(logBodyKey, logged)
+			return
+		}
+		if id := c.GetString(requestIDKey); id != "" {
+			log.Printf("Request body %s %s [%s]: %q", c.Request.Method, c.Request.URL.Path, id, logged)
+		} else {
+			log.Printf("Request body %s %s: %q", c.Request.Method, c.Request.URL.Path, logged)
+		}
+	}
+}
+
+// jsonLogger 每个请求输出一行 JSON，便于日志系统解析
+func jsonLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		if raw := c.Request.URL.RawQuery; raw != "" {
+			path += "?" + raw
+		}
+
+		c.Next()
+
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		entry := accessLogEntry{
+			Time:      start.Format(time.RFC3339),
+			Method:    c.Request.Method,
+			Path:      path,
+			Status:    c.Writer.Status(),
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			ClientIP:  c.ClientIP(),
+			Size:      size,
+			RequestID: c.GetString(requestIDKey),
+			Error:     strings.Join(c.Errors.Errors(), "; "),
+		}
+		if body, ok := c.Get(logBodyKey); ok {
+			logged := body.(string)
+			entry.RequestBody = &logged
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(gin.DefaultWriter, string(line))
+	}
+}
+
+// textLogFormatter 与 gin 默认的访问日志格式相同，末尾追加请求 ID
+func textLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	requestID, _ := param.Keys[requestIDKey].(string)
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v | %s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		param.Path,
+		requestID,
+		param.ErrorMessage,
+	)
+}
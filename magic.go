@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	magicDelayParam  = "__delay"
+	magicStatusParam = "__status"
+)
+
+// magicOverrides 是 -magic-params 模式下通过查询参数临时覆盖的延迟和状态码
+type magicOverrides struct {
+	delay      time.Duration
+	hasDelay   bool
+	statusCode int
+}
+
+// takeMagicParams 读取并移除请求中的 __delay、__status 查询参数，
+// 避免它们影响后续的匹配规则和模板渲染
+func takeMagicParams(c *gin.Context) (magicOverrides, error) {
+	var o magicOverrides
+	query := c.Request.URL.Query()
+	if _, ok := query[magicDelayParam]; !ok {
+		if _, ok := query[magicStatusParam]; !ok {
+			return o, nil
+		}
+	}
+
+	if v := query.Get(magicDelayParam); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return o, fmt.Errorf("invalid %s %q", magicDelayParam, v)
+		}
+		o.delay, o.hasDelay = d, true
+	}
+	if v := query.Get(magicStatusParam); v != "" {
+		code, err := strconv.Atoi(v)
+		if err != nil || code < 100 || code > 599 {
+			return o, fmt.Errorf("invalid %s %q", magicStatusParam, v)
+		}
+		o.statusCode = code
+	}
+
+	query.Del(magicDelayParam)
+	query.Del(magicStatusParam)
+	c.Request.URL.RawQuery = query.Encode()
+	return o, nil
+}
@@ -1,98 +1,1873 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"mime"
 	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/template"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/gin-gonic/gin"
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"gopkg.in/yaml.v2"
 )
 
 type Endpoint struct {
-	Path         string `yaml:"path"`
-	Method       string `yaml:"method"`
-	ResponseFile string `yaml:"responseFile"`
+	Type               string             `yaml:"type" json:"type" toml:"type"`
+	Path               string             `yaml:"path" json:"path" toml:"path"`
+	Method             string             `yaml:"-" json:"-" toml:"-"`
+	Methods            MethodList         `yaml:"method" json:"method" toml:"method"`
+	ResponseFile       string             `yaml:"responseFile" json:"responseFile" toml:"responseFile"`
+	ResponseBody       string             `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+	ResponseBodyBase64 string             `yaml:"responseBodyBase64" json:"responseBodyBase64" toml:"responseBodyBase64"`
+	StatusCode         int                `yaml:"statusCode" json:"statusCode" toml:"statusCode"`
+	Headers            map[string]string  `yaml:"headers" json:"headers" toml:"headers"`
+	Delay              Duration           `yaml:"delay" json:"delay" toml:"delay"`
+	Jitter             Duration           `yaml:"jitter" json:"jitter" toml:"jitter"`
+	SpikeRate          float64            `yaml:"spikeRate" json:"spikeRate" toml:"spikeRate"`
+	SpikeDelay         Duration           `yaml:"spikeDelay" json:"spikeDelay" toml:"spikeDelay"`
+	Template           bool               `yaml:"template" json:"template" toml:"template"`
+	Matches            []MatchRule        `yaml:"matches" json:"matches" toml:"matches"`
+	Echo               bool               `yaml:"echo" json:"echo" toml:"echo"`
+	Responses          []WeightedResponse `yaml:"responses" json:"responses" toml:"responses"`
+	Sequence           []SequenceStep     `yaml:"sequence" json:"sequence" toml:"sequence"`
+	SequenceMode       string             `yaml:"sequenceMode" json:"sequenceMode" toml:"sequenceMode"`
+	Scenario           *EndpointScenario  `yaml:"scenario" json:"scenario" toml:"scenario"`
+	FaultRate          float64            `yaml:"faultRate" json:"faultRate" toml:"faultRate"`
+	FaultStatus        int                `yaml:"faultStatus" json:"faultStatus" toml:"faultStatus"`
+	FaultBody          string             `yaml:"faultBody" json:"faultBody" toml:"faultBody"`
+	RateLimit          *RateLimitConfig   `yaml:"rateLimit" json:"rateLimit" toml:"rateLimit"`
+	Variants           []ResponseVariant  `yaml:"variants" json:"variants" toml:"variants"`
+	Cookies            []ResponseCookie   `yaml:"cookies" json:"cookies" toml:"cookies"`
+	ETag               bool               `yaml:"etag" json:"etag" toml:"etag"`
+	Reflect            map[string]string  `yaml:"reflect" json:"reflect" toml:"reflect"`
+	Transform          []TransformOp      `yaml:"transform" json:"transform" toml:"transform"`
+	Timeout            Duration           `yaml:"timeout" json:"timeout" toml:"timeout"`
+	TimeoutBody        string             `yaml:"timeoutBody" json:"timeoutBody" toml:"timeoutBody"`
+	Location           string             `yaml:"location" json:"location" toml:"location"`
+	Parts              []MultipartPart    `yaml:"parts" json:"parts" toml:"parts"`
+	MultipartType      string             `yaml:"multipartType" json:"multipartType" toml:"multipartType"`
+	GRPCStatus         int                `yaml:"grpcStatus" json:"grpcStatus" toml:"grpcStatus"`
+	GRPCMessage        string             `yaml:"grpcMessage" json:"grpcMessage" toml:"grpcMessage"`
+	Disabled           bool               `yaml:"disabled" json:"disabled" toml:"disabled"`
+	LogBody            bool               `yaml:"logBody" json:"logBody" toml:"logBody"`
+	Throttle           int64              `yaml:"throttle" json:"throttle" toml:"throttle"`
+	FileSelect         string             `yaml:"fileSelect" json:"fileSelect" toml:"fileSelect"`
+	CacheTTL           Duration           `yaml:"cacheTTL" json:"cacheTTL" toml:"cacheTTL"`
+	Schema             string             `yaml:"schema" json:"schema" toml:"schema"`
+	Script             string             `yaml:"script" json:"script" toml:"script"`
+	Command            []string           `yaml:"command" json:"command" toml:"command"`
+	ExitStatus         map[string]int     `yaml:"exitStatus" json:"exitStatus" toml:"exitStatus"`
+	GzipMode           string             `yaml:"gzipMode" json:"gzipMode" toml:"gzipMode"`
+	CallLimit          int                `yaml:"callLimit" json:"callLimit" toml:"callLimit"`
+	OverLimitStatus    int                `yaml:"overLimitStatus" json:"overLimitStatus" toml:"overLimitStatus"`
+	OverLimitFile      string             `yaml:"overLimitFile" json:"overLimitFile" toml:"overLimitFile"`
+	OverLimitBody      string             `yaml:"overLimitBody" json:"overLimitBody" toml:"overLimitBody"`
+	// PathRegex 为 true 时 path 是正则表达式，只在没有普通路由匹配时才尝试
+	PathRegex bool `yaml:"pathRegex" json:"pathRegex" toml:"pathRegex"`
+
+	// allowEmpty 表示允许没有任何响应内容，用于从 OpenAPI 等外部文档导入的端点
+	allowEmpty bool
+	// globFiles 是 responseFile 为通配符时在加载时展开得到的文件
+	globFiles []string
+}
+
+// WeightedResponse 是 responses 列表中的一项，每次请求按权重随机选择一项
+type WeightedResponse struct {
+	Weight       int    `yaml:"weight" json:"weight" toml:"weight"`
+	StatusCode   int    `yaml:"statusCode" json:"statusCode" toml:"statusCode"`
+	ResponseFile string `yaml:"responseFile" json:"responseFile" toml:"responseFile"`
+	ResponseBody string `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+}
+
+type Service struct {
+	Name      string      `yaml:"name" json:"name" toml:"name"`
+	BasePath  string      `yaml:"basePath" json:"basePath" toml:"basePath"`
+	CORS      *CORSConfig `yaml:"cors" json:"cors" toml:"cors"`
+	Auth      *AuthConfig `yaml:"auth" json:"auth" toml:"auth"`
+	Endpoints []Endpoint  `yaml:"endpoints" json:"endpoints" toml:"endpoints"`
+	// ResponseDir 不为空时，端点中的相对 responseFile 都相对于该目录，否则相对于配置文件所在目录
+	ResponseDir string `yaml:"responseDir" json:"responseDir" toml:"responseDir"`
+	Disabled    bool   `yaml:"disabled" json:"disabled" toml:"disabled"`
+	// Host 不为空时，服务只处理 Host 头与之匹配的请求
+	Host string `yaml:"host" json:"host" toml:"host"`
+	// Port 不为 0 时，服务的端点只在该端口的独立监听器上提供
+	Port int `yaml:"port" json:"port" toml:"port"`
+
+	// source 记录服务来自哪个配置文件，用于错误提示
+	source string
+}
+
+type ServerConfig struct {
+	Address      string           `yaml:"address" json:"address" toml:"address"`
+	Port         int              `yaml:"port" json:"port" toml:"port"`
+	CORS         *CORSConfig      `yaml:"cors" json:"cors" toml:"cors"`
+	TLS          TLSConfig        `yaml:"tls" json:"tls" toml:"tls"`
+	MaxBodyBytes int64            `yaml:"maxBodyBytes" json:"maxBodyBytes" toml:"maxBodyBytes"`
+	RateLimit    *RateLimitConfig `yaml:"rateLimit" json:"rateLimit" toml:"rateLimit"`
+	// RecordDir 不为空时，每个请求和响应都会记录为该目录下的一个文件
+	RecordDir    string `yaml:"recordDir" json:"recordDir" toml:"recordDir"`
+	RecordBodies *bool  `yaml:"recordBodies" json:"recordBodies" toml:"recordBodies"`
+	// StreamThreshold 为响应文件大小阈值（字节），达到阈值的非模板响应直接从磁盘流式输出
+	StreamThreshold int64 `yaml:"streamThreshold" json:"streamThreshold" toml:"streamThreshold"`
+	// CaseInsensitive 和 RedirectTrailingSlash 对应 gin 的 RedirectFixedPath 和 RedirectTrailingSlash，
+	// 命中时返回重定向（GET 为 301，其余方法为 307），而不是直接执行端点
+	CaseInsensitive       bool `yaml:"caseInsensitive" json:"caseInsensitive" toml:"caseInsensitive"`
+	RedirectTrailingSlash bool `yaml:"redirectTrailingSlash" json:"redirectTrailingSlash" toml:"redirectTrailingSlash"`
+	// 未配置时使用 defaultReadTimeout 等默认值；writeTimeout 默认不限制，以免截断 SSE 等长连接响应
+	ReadTimeout  Duration `yaml:"readTimeout" json:"readTimeout" toml:"readTimeout"`
+	WriteTimeout Duration `yaml:"writeTimeout" json:"writeTimeout" toml:"writeTimeout"`
+	IdleTimeout  Duration `yaml:"idleTimeout" json:"idleTimeout" toml:"idleTimeout"`
+	// LogBodyMax 是 logBody 端点记录请求体的最大字节数，默认 defaultLogBodyMax
+	LogBodyMax int `yaml:"logBodyMax" json:"logBodyMax" toml:"logBodyMax"`
+	// BasePath 加在所有服务的 basePath 之前，用于挂在网关的路径前缀下
+	BasePath string `yaml:"basePath" json:"basePath" toml:"basePath"`
+	// Throttle 是响应体的带宽上限（字节/秒），端点的 throttle 优先，0 表示不限速
+	Throttle int64 `yaml:"throttle" json:"throttle" toml:"throttle"`
+	// TemplateDir 中的文件作为 partial 加载，模板响应可以用 {{ template "name" . }} 引用
+	TemplateDir string `yaml:"templateDir" json:"templateDir" toml:"templateDir"`
+}
+
+func orDefault(d Duration, def time.Duration) time.Duration {
+	if d > 0 {
+		return time.Duration(d)
+	}
+	return def
+}
+
+type TLSConfig struct {
+	CertFile string `yaml:"certFile" json:"certFile" toml:"certFile"`
+	KeyFile  string `yaml:"keyFile" json:"keyFile" toml:"keyFile"`
+	// ClientCAFile 用于校验客户端证书；RequireClientCert 为 true 时没有可信证书的客户端在握手阶段即被拒绝
+	ClientCAFile      string `yaml:"clientCAFile" json:"clientCAFile" toml:"clientCAFile"`
+	RequireClientCert bool   `yaml:"requireClientCert" json:"requireClientCert" toml:"requireClientCert"`
+}
+
+func (t TLSConfig) enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// serverTLSConfig 返回 http.Server 使用的 tls.Config，未配置客户端 CA 时返回 nil
+func (t TLSConfig) serverTLSConfig() (*tls.Config, error) {
+	if t.ClientCAFile == "" {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(t.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", t.ClientCAFile)
+	}
+	clientAuth := tls.VerifyClientCertIfGiven
+	if t.RequireClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	return &tls.Config{ClientCAs: pool, ClientAuth: clientAuth}, nil
+}
+
+// NotFoundConfig 定义未匹配任何路由时返回的响应，未配置时使用 gin 默认的 404
+type NotFoundConfig struct {
+	StatusCode   int               `yaml:"statusCode" json:"statusCode" toml:"statusCode"`
+	ResponseFile string            `yaml:"responseFile" json:"responseFile" toml:"responseFile"`
+	ResponseBody string            `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+	Headers      map[string]string `yaml:"headers" json:"headers" toml:"headers"`
+}
+
+func (n *NotFoundConfig) endpoint() Endpoint {
+	statusCode := n.StatusCode
+	if statusCode == 0 {
+		statusCode = 404
+	}
+	return Endpoint{
+		ResponseFile: n.ResponseFile,
+		ResponseBody: n.ResponseBody,
+		StatusCode:   statusCode,
+		Headers:      n.Headers,
+	}
+}
+
+// StaticMapping 将 URL 前缀映射到本地目录，目录外的文件无法访问
+type StaticMapping struct {
+	Prefix string `yaml:"prefix" json:"prefix" toml:"prefix"`
+	Dir    string `yaml:"dir" json:"dir" toml:"dir"`
+}
+
+type Config struct {
+	Port      int             `yaml:"port" json:"port" toml:"port"`
+	Server    ServerConfig    `yaml:"server" json:"server" toml:"server"`
+	NotFound  *NotFoundConfig `yaml:"notFound" json:"notFound" toml:"notFound"`
+	Fallback  *FallbackConfig `yaml:"fallback" json:"fallback" toml:"fallback"`
+	Upstream  string          `yaml:"upstream" json:"upstream" toml:"upstream"`
+	Static    []StaticMapping `yaml:"static" json:"static" toml:"static"`
+	Scenarios []Scenario      `yaml:"scenarios" json:"scenarios" toml:"scenarios"`
+	Services  []Service       `yaml:"services" json:"services" toml:"services"`
+	// Include 列出要合并进来的其他配置文件，相对路径相对于当前文件
+	Include []string `yaml:"include" json:"include" toml:"include"`
+
+	// files 是加载时读取过的所有配置文件，包括 include 的文件
+	files []string
+	// partials 是从 server.templateDir 解析出的模板，partialFiles 是其中的文件，重载时一并监控
+	partials     *template.Template
+	partialFiles []string
+	// caches 索引本配置构建出的端点缓存，响应文件变化时按文件清空
+	caches *cacheIndex
+}
+
+const (
+	defaultPort            = 8080
+	shutdownTimeout        = 10 * time.Second
+	defaultReadTimeout     = 30 * time.Second
+	defaultIdleTimeout     = 120 * time.Second
+	defaultStreamThreshold = 1 << 20
+)
+
+// loadOptions 控制配置加载行为，由命令行参数决定
+type loadOptions struct {
+	StrictEnv bool
+	// StrictJSON 为 true 时，以 JSON 类型返回的响应文件必须是合法的 JSON
+	StrictJSON bool
+	// OpenAPIFile、PostmanFile 和 HARFile 不为空时，从中生成的端点会与配置文件合并
+	OpenAPIFile string
+	PostmanFile string
+	HARFile     string
+	// AllowExec 为 true 时才允许加载 exec 类型的端点
+	AllowExec bool
+}
+
+// loadConfig 加载配置文件；path 为目录时加载其中所有配置文件并合并
+func loadConfig(path string, opts loadOptions) (*Config, error) {
+	files, err := configFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	loader := newConfigLoader(opts)
+	for _, file := range files {
+		if err := loader.load(&config, file); err != nil {
+			return nil, err
+		}
+	}
+	config.files = loader.files
+
+	if opts.OpenAPIFile != "" {
+		service, err := loadOpenAPI(opts.OpenAPIFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", opts.OpenAPIFile, err)
+		}
+		config.Services = append(config.Services, *service)
+	}
+	if opts.PostmanFile != "" {
+		services, err := loadPostman(opts.PostmanFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", opts.PostmanFile, err)
+		}
+		config.Services = append(config.Services, services...)
+	}
+	if opts.HARFile != "" {
+		service, err := loadHAR(opts.HARFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", opts.HARFile, err)
+		}
+		config.Services = append(config.Services, *service)
+	}
+
+	if err := applyGlobalBasePath(&config); err != nil {
+		return nil, err
+	}
+	if err := checkExecAllowed(&config, opts.AllowExec); err != nil {
+		return nil, err
+	}
+	if err := validateConfig(&config); err != nil {
+		return nil, err
+	}
+	if config.Server.TemplateDir != "" {
+		config.partials, config.partialFiles, err = loadPartials(config.Server.TemplateDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := validateTemplates(&config); err != nil {
+		return nil, err
+	}
+	if err := validateSchemas(&config); err != nil {
+		return nil, err
+	}
+	if opts.StrictJSON {
+		if err := validateJSONFiles(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	return &config, nil
+}
+
+// applyGlobalBasePath 把 server.basePath 加到每个服务的 basePath 之前，包括从 OpenAPI 等文档导入的服务
+func applyGlobalBasePath(config *Config) error {
+	prefix := strings.TrimSuffix(config.Server.BasePath, "/")
+	if prefix == "" {
+		config.Server.BasePath = ""
+		return nil
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return fmt.Errorf("server basePath %q must start with /", config.Server.BasePath)
+	}
+	config.Server.BasePath = prefix
+	for i := range config.Services {
+		config.Services[i].BasePath = prefix + config.Services[i].BasePath
+	}
+	return nil
+}
+
+// configFiles 返回 path 对应的配置文件列表，目录按文件名排序
+func configFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isConfigFile(entry.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no config files found in directory %s", path)
+	}
+	return files, nil
+}
+
+// isConfigFile 判断目录中的文件是否作为配置加载。目录中的 .json 通常是响应文件，
+// 因此目录模式只加载 yaml 和 toml，JSON 配置需要直接用 -config 指定
+func isConfigFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yaml" || ext == ".yml" || ext == ".toml"
+}
+
+// unmarshalConfig 根据文件扩展名选择解析格式
+func unmarshalConfig(filename string, data []byte, config *Config) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(data, config)
+	case ".json":
+		return json.Unmarshal(data, config)
+	case ".toml":
+		return toml.NewDecoder(bytes.NewReader(data)).EnableUnmarshalerInterface().Decode(config)
+	default:
+		return fmt.Errorf("unsupported config format %q, expected .yaml, .yml, .json or .toml", filepath.Ext(filename))
+	}
+}
+
+func parseConfigFile(filename string, opts loadOptions) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = expandEnv(data, opts.StrictEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	err = unmarshalConfig(filename, data, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	removeDisabled(&config)
+	if err := expandMethods(&config); err != nil {
+		return nil, err
+	}
+	configDir := filepath.Dir(filename)
+	if config.NotFound != nil {
+		config.NotFound.ResponseFile = resolvePath(configDir, config.NotFound.ResponseFile)
+	}
+	config.Server.TemplateDir = resolvePath(configDir, config.Server.TemplateDir)
+	for i := range config.Static {
+		config.Static[i].Dir = resolvePath(configDir, config.Static[i].Dir)
+	}
+	for i := range config.Services {
+		config.Services[i].resolveFiles(configDir)
+		host, err := normalizeHost(config.Services[i].Host)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %v", config.Services[i].Name, err)
+		}
+		config.Services[i].Host = host
+	}
+	if err := expandGlobs(&config); err != nil {
+		return nil, err
+	}
+	for i := range config.Services {
+		config.Services[i].source = filename
+	}
+	return &config, nil
 }
 
-type Service struct {
-	Name      string     `yaml:"name"`
-	BasePath  string     `yaml:"basePath"`
-	Endpoints []Endpoint `yaml:"endpoints"`
+// removeDisabled 丢弃 disabled 的服务和端点，它们不参与校验也不注册路由，请求会落到 404/upstream
+func removeDisabled(config *Config) {
+	services := config.Services[:0]
+	for _, service := range config.Services {
+		if service.Disabled {
+			log.Printf("Skipping disabled service %s (%d endpoints)", service.Name, len(service.Endpoints))
+			continue
+		}
+		endpoints := service.Endpoints[:0]
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Disabled {
+				log.Printf("Skipping disabled endpoint %s %s in service %s", strings.Join(endpoint.Methods, ","), service.BasePath+endpoint.Path, service.Name)
+				continue
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		service.Endpoints = endpoints
+		services = append(services, service)
+	}
+	config.Services = services
+}
+
+// mergeConfig 将 src 的服务追加到 dst，其余全局配置以先出现的文件为准
+func mergeConfig(dst, src *Config) {
+	if dst.Port == 0 {
+		dst.Port = src.Port
+	}
+	if dst.Server == (ServerConfig{}) {
+		dst.Server = src.Server
+	}
+	if dst.NotFound == nil {
+		dst.NotFound = src.NotFound
+	}
+	if dst.Fallback == nil {
+		dst.Fallback = src.Fallback
+	}
+	if dst.Upstream == "" {
+		dst.Upstream = src.Upstream
+	}
+	dst.Static = append(dst.Static, src.Static...)
+	dst.Scenarios = append(dst.Scenarios, src.Scenarios...)
+	dst.Services = append(dst.Services, src.Services...)
+}
+
+// expandEnv 替换配置中的 ${VAR} / $VAR 环境变量引用，$$ 表示字面量 $。
+// strict 为 true 时引用未定义的变量会返回错误
+func expandEnv(data []byte, strict bool) ([]byte, error) {
+	var undefined []string
+	expanded := os.Expand(string(data), func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			undefined = append(undefined, name)
+		}
+		return value
+	})
+	if strict && len(undefined) > 0 {
+		return nil, fmt.Errorf("undefined environment variables in config: %s", strings.Join(undefined, ", "))
+	}
+	return []byte(expanded), nil
+}
+
+func validateConfig(config *Config) error {
+	if err := checkDuplicateRoutes(config); err != nil {
+		return err
+	}
+	if (config.Server.TLS.CertFile == "") != (config.Server.TLS.KeyFile == "") {
+		return fmt.Errorf("server.tls requires both certFile and keyFile")
+	}
+	if tlsConfig := config.Server.TLS; tlsConfig.ClientCAFile != "" || tlsConfig.RequireClientCert {
+		if !tlsConfig.enabled() {
+			return fmt.Errorf("server.tls clientCAFile and requireClientCert require certFile and keyFile")
+		}
+		if tlsConfig.ClientCAFile == "" {
+			return fmt.Errorf("server.tls requireClientCert requires clientCAFile")
+		}
+		if _, err := tlsConfig.serverTLSConfig(); err != nil {
+			return fmt.Errorf("server.tls clientCAFile: %v", err)
+		}
+	}
+	if config.Server.ReadTimeout < 0 || config.Server.WriteTimeout < 0 || config.Server.IdleTimeout < 0 {
+		return fmt.Errorf("server readTimeout, writeTimeout and idleTimeout must not be negative")
+	}
+	if config.Server.Throttle < 0 {
+		return fmt.Errorf("server throttle must not be negative")
+	}
+	if config.Server.RateLimit != nil {
+		if err := config.Server.RateLimit.validate(); err != nil {
+			return fmt.Errorf("server: %v", err)
+		}
+	}
+	if config.Server.CORS != nil {
+		if err := config.Server.CORS.validate(); err != nil {
+			return fmt.Errorf("server: %v", err)
+		}
+	}
+	if config.Upstream != "" {
+		if _, err := parseUpstream(config.Upstream); err != nil {
+			return err
+		}
+	}
+	if err := validateStatic(config); err != nil {
+		return err
+	}
+	if config.NotFound != nil && config.NotFound.ResponseFile == "" && config.NotFound.ResponseBody == "" {
+		return fmt.Errorf("notFound has neither responseFile nor responseBody")
+	}
+
+	scenarioNames := make(map[string]bool)
+	for _, sc := range config.Scenarios {
+		if err := sc.validate(); err != nil {
+			return err
+		}
+		if scenarioNames[sc.Name] {
+			return fmt.Errorf("duplicate scenario %s", sc.Name)
+		}
+		scenarioNames[sc.Name] = true
+	}
+
+	for _, service := range config.Services {
+		if service.Port < 0 || service.Port > 65535 {
+			return fmt.Errorf("service %s has invalid port %d", service.Name, service.Port)
+		}
+		if service.CORS != nil {
+			if err := service.CORS.validate(); err != nil {
+				return fmt.Errorf("service %s: %v", service.Name, err)
+			}
+		}
+		if service.Auth != nil {
+			if err := service.Auth.validate(); err != nil {
+				return fmt.Errorf("service %s: %v", service.Name, err)
+			}
+		}
+		for _, endpoint := range service.Endpoints {
+			fullPath := service.BasePath + endpoint.Path
+			switch endpoint.Type {
+			case "", endpointTypeSSE:
+			case endpointTypeWebSocket:
+				if !strings.EqualFold(endpoint.Method, "GET") {
+					return fmt.Errorf("websocket endpoint %s in service %s must use method GET", fullPath, service.Name)
+				}
+			case endpointTypeRedirect:
+				if err := validateRedirect(endpoint); err != nil {
+					return fmt.Errorf("endpoint %s %s in service %s: %v", endpoint.Method, fullPath, service.Name, err)
+				}
+			case endpointTypeMultipart:
+				if err := validateMultipart(endpoint); err != nil {
+					return fmt.Errorf("endpoint %s %s in service %s: %v", endpoint.Method, fullPath, service.Name, err)
+				}
+			case endpointTypeGRPCWeb:
+				if err := validateGRPCWeb(endpoint); err != nil {
+					return fmt.Errorf("endpoint %s %s in service %s: %v", endpoint.Method, fullPath, service.Name, err)
+				}
+			case endpointTypeExec:
+				if err := validateExec(endpoint); err != nil {
+					return fmt.Errorf("endpoint %s %s in service %s: %v", endpoint.Method, fullPath, service.Name, err)
+				}
+			default:
+				return fmt.Errorf("endpoint %s %s in service %s has unsupported type %q", endpoint.Method, fullPath, service.Name, endpoint.Type)
+			}
+			if endpoint.needsResponse() && !endpoint.hasResponse() {
+				return fmt.Errorf("endpoint %s %s in service %s has neither responseFile nor responseBody", endpoint.Method, fullPath, service.Name)
+			}
+			if endpoint.ResponseBodyBase64 != "" {
+				if endpoint.ResponseBody != "" {
+					return fmt.Errorf("endpoint %s %s in service %s sets both responseBody and responseBodyBase64", endpoint.Method, fullPath, service.Name)
+				}
+				if _, err := base64.StdEncoding.DecodeString(endpoint.ResponseBodyBase64); err != nil {
+					return fmt.Errorf("endpoint %s %s in service %s has invalid responseBodyBase64: %v", endpoint.Method, fullPath, service.Name, err)
+				}
+			}
+			if endpoint.ResponseFile != "" && endpoint.ResponseBody != "" {
+				log.Printf("Warning: endpoint %s %s in service %s sets both responseFile and responseBody, using responseFile", endpoint.Method, fullPath, service.Name)
+			}
+			for i, rule := range endpoint.Matches {
+				if !endpoint.allowEmpty && rule.ResponseFile == "" && rule.ResponseBody == "" {
+					return fmt.Errorf("match rule %d of endpoint %s %s in service %s has neither responseFile nor responseBody", i, endpoint.Method, fullPath, service.Name)
+				}
+			}
+			for i, resp := range endpoint.Responses {
+				if resp.ResponseFile == "" && resp.ResponseBody == "" {
+					return fmt.Errorf("response %d of endpoint %s %s in service %s has neither responseFile nor responseBody", i, endpoint.Method, fullPath, service.Name)
+				}
+				if resp.Weight < 0 {
+					return fmt.Errorf("response %d of endpoint %s %s in service %s has a negative weight", i, endpoint.Method, fullPath, service.Name)
+				}
+			}
+			for i, step := range endpoint.Sequence {
+				if !endpoint.allowEmpty && step.ResponseFile == "" && step.ResponseBody == "" {
+					return fmt.Errorf("sequence step %d of endpoint %s %s in service %s has neither responseFile nor responseBody", i, endpoint.Method, fullPath, service.Name)
+				}
+			}
+			for i, variant := range endpoint.Variants {
+				if variant.MediaType == "" {
+					return fmt.Errorf("variant %d of endpoint %s %s in service %s has no mediaType", i, endpoint.Method, fullPath, service.Name)
+				}
+				if variant.ResponseFile == "" && variant.ResponseBody == "" {
+					return fmt.Errorf("variant %d of endpoint %s %s in service %s has neither responseFile nor responseBody", i, endpoint.Method, fullPath, service.Name)
+				}
+			}
+			for i, cookie := range endpoint.Cookies {
+				if cookie.Name == "" {
+					return fmt.Errorf("cookie %d of endpoint %s %s in service %s has no name", i, endpoint.Method, fullPath, service.Name)
+				}
+			}
+			if endpoint.RateLimit != nil {
+				if err := endpoint.RateLimit.validate(); err != nil {
+					return fmt.Errorf("endpoint %s %s in service %s: %v", endpoint.Method, fullPath, service.Name, err)
+				}
+			}
+			if sc := endpoint.Scenario; sc != nil {
+				if !scenarioNames[sc.Name] {
+					return fmt.Errorf("endpoint %s %s in service %s references undefined scenario %q", endpoint.Method, fullPath, service.Name, sc.Name)
+				}
+				for i, state := range sc.States {
+					if state.State == "" {
+						return fmt.Errorf("scenario state %d of endpoint %s %s in service %s has no state", i, endpoint.Method, fullPath, service.Name)
+					}
+					if state.ResponseFile == "" && state.ResponseBody == "" {
+						return fmt.Errorf("scenario state %d of endpoint %s %s in service %s has neither responseFile nor responseBody", i, endpoint.Method, fullPath, service.Name)
+					}
+				}
+			}
+			for i, op := range endpoint.Transform {
+				if err := op.validate(); err != nil {
+					return fmt.Errorf("transform %d of endpoint %s %s in service %s: %v", i, endpoint.Method, fullPath, service.Name, err)
+				}
+			}
+			for field, path := range endpoint.Reflect {
+				if _, err := parseJSONPath(path); err != nil {
+					return fmt.Errorf("reflect field %s of endpoint %s %s in service %s: %v", field, endpoint.Method, fullPath, service.Name, err)
+				}
+			}
+			if endpoint.PathRegex {
+				if _, err := compilePathRegex(service.BasePath, endpoint.Path); err != nil {
+					return fmt.Errorf("endpoint %s %s in service %s has invalid pathRegex: %v", endpoint.Method, fullPath, service.Name, err)
+				}
+			}
+			if endpoint.Timeout < 0 {
+				return fmt.Errorf("endpoint %s %s in service %s has a negative timeout", endpoint.Method, fullPath, service.Name)
+			}
+			if endpoint.Script != "" {
+				if endpoint.Type != "" {
+					return fmt.Errorf("%s endpoint %s in service %s does not support script", endpoint.Type, fullPath, service.Name)
+				}
+				if _, err := compileScript(endpoint.Script); err != nil {
+					return fmt.Errorf("endpoint %s %s in service %s has an invalid script: %v", endpoint.Method, fullPath, service.Name, err)
+				}
+			}
+			if endpoint.CallLimit < 0 {
+				return fmt.Errorf("endpoint %s %s in service %s has a negative callLimit", endpoint.Method, fullPath, service.Name)
+			}
+			if endpoint.CallLimit > 0 && endpoint.Type != "" {
+				return fmt.Errorf("%s endpoint %s in service %s does not support callLimit", endpoint.Type, fullPath, service.Name)
+			}
+			if endpoint.CacheTTL < 0 {
+				return fmt.Errorf("endpoint %s %s in service %s has a negative cacheTTL", endpoint.Method, fullPath, service.Name)
+			}
+			if endpoint.CacheTTL > 0 && (endpoint.Type == endpointTypeSSE || endpoint.Type == endpointTypeWebSocket) {
+				return fmt.Errorf("%s endpoint %s in service %s does not support cacheTTL", endpoint.Type, fullPath, service.Name)
+			}
+			if reason := uncacheableBy(endpoint); endpoint.CacheTTL > 0 && reason != "" {
+				return fmt.Errorf("endpoint %s %s in service %s sets cacheTTL but %s, which the cache key (method and request URI) does not distinguish", endpoint.Method, fullPath, service.Name, reason)
+			}
+			if endpoint.Throttle < 0 {
+				return fmt.Errorf("endpoint %s %s in service %s has a negative throttle", endpoint.Method, fullPath, service.Name)
+			}
+			if endpoint.Timeout > 0 && endpoint.Type == endpointTypeWebSocket {
+				return fmt.Errorf("websocket endpoint %s in service %s does not support timeout", fullPath, service.Name)
+			}
+			if endpoint.Delay < 0 || endpoint.Jitter < 0 {
+				return fmt.Errorf("endpoint %s %s in service %s has a negative delay or jitter", endpoint.Method, fullPath, service.Name)
+			}
+			if endpoint.SpikeRate < 0 || endpoint.SpikeRate > 1 {
+				return fmt.Errorf("endpoint %s %s in service %s has spikeRate %v outside [0, 1]", endpoint.Method, fullPath, service.Name, endpoint.SpikeRate)
+			}
+			if endpoint.SpikeRate > 0 && endpoint.SpikeDelay <= 0 {
+				return fmt.Errorf("endpoint %s %s in service %s sets spikeRate without a positive spikeDelay", endpoint.Method, fullPath, service.Name)
+			}
+			if endpoint.FaultRate < 0 || endpoint.FaultRate > 1 {
+				return fmt.Errorf("endpoint %s %s in service %s has faultRate %v outside [0, 1]", endpoint.Method, fullPath, service.Name, endpoint.FaultRate)
+			}
+			if mode := endpoint.GzipMode; mode != "" && mode != gzipModeAuto && mode != gzipModeDecompress && mode != gzipModeRaw {
+				return fmt.Errorf("endpoint %s %s in service %s has invalid gzipMode %q, expected auto, decompress or raw", endpoint.Method, fullPath, service.Name, mode)
+			}
+			if mode := endpoint.FileSelect; mode != "" && mode != fileSelectRandom && mode != fileSelectRoundRobin {
+				return fmt.Errorf("endpoint %s %s in service %s has invalid fileSelect %q, expected random or roundRobin", endpoint.Method, fullPath, service.Name, mode)
+			}
+			if mode := endpoint.SequenceMode; mode != "" && mode != sequenceModeStick && mode != sequenceModeWrap {
+				return fmt.Errorf("endpoint %s %s in service %s has invalid sequenceMode %q, expected stick or wrap", endpoint.Method, fullPath, service.Name, mode)
+			}
+		}
+	}
+	return validateResponseFiles(config)
+}
+
+// validateStatic 检查静态目录存在，且前缀不会与 mock 端点冲突
+func validateStatic(config *Config) error {
+	for _, m := range config.Static {
+		if !strings.HasPrefix(m.Prefix, "/") {
+			return fmt.Errorf("static prefix %q must start with /", m.Prefix)
+		}
+		info, err := os.Stat(m.Dir)
+		if err != nil {
+			return fmt.Errorf("static dir for %s: %v", m.Prefix, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("static dir for %s: %s is not a directory", m.Prefix, m.Dir)
+		}
+
+		prefix := strings.TrimSuffix(m.Prefix, "/")
+		for _, service := range config.Services {
+			for _, endpoint := range service.Endpoints {
+				fullPath := service.BasePath + endpoint.Path
+				if fullPath == prefix || strings.HasPrefix(fullPath, prefix+"/") {
+					return fmt.Errorf("static prefix %s conflicts with endpoint %s %s in service %s", m.Prefix, endpoint.Method, fullPath, service.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkDuplicateRoutes 检查是否有多个端点定义了相同的 method+path，
+// 避免 gin 注册路由时 panic
+func checkDuplicateRoutes(config *Config) error {
+	seen := make(map[string]Service)
+	for _, service := range config.Services {
+		for _, endpoint := range service.Endpoints {
+			key := routeKey(service, endpoint)
+			if first, ok := seen[key]; ok {
+				return fmt.Errorf("duplicate route %s defined in service %s and service %s", key, first.describe(), service.describe())
+			}
+			seen[key] = service
+		}
+	}
+	return nil
+}
+
+func (s Service) describe() string {
+	if s.source == "" {
+		return s.Name
+	}
+	return fmt.Sprintf("%s (%s)", s.Name, s.source)
+}
+
+// validateResponseFiles 检查所有 responseFile 是否存在，并一次性列出所有缺失的文件
+func validateResponseFiles(config *Config) error {
+	var missing []string
+	for _, service := range config.Services {
+		for _, endpoint := range service.Endpoints {
+			fullPath := service.BasePath + endpoint.Path
+			for _, file := range endpointFiles(endpoint) {
+				if _, err := os.Stat(file); err != nil {
+					missing = append(missing, fmt.Sprintf("%s (endpoint %s %s in service %s): %v", file, endpoint.Method, fullPath, service.Name, err))
+				}
+			}
+		}
+	}
+	if config.NotFound != nil && config.NotFound.ResponseFile != "" {
+		if _, err := os.Stat(config.NotFound.ResponseFile); err != nil {
+			missing = append(missing, fmt.Sprintf("%s (notFound): %v", config.NotFound.ResponseFile, err))
+		}
+	}
+	if len(missing) > 0 {
+		// 配置了 fallback 时允许 fixture 暂时缺失，请求时返回 fallback
+		if config.Fallback != nil {
+			log.Printf("Warning: missing response files, fallback will be served:\n  %s", strings.Join(missing, "\n  "))
+			return nil
+		}
+		return fmt.Errorf("missing response files:\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
+
+// validateJSONFiles 检查所有以 JSON 类型返回的响应文件能否解析。
+// 模板端点的文件在渲染后才是最终内容，SSE 和 WebSocket 端点的文件是事件脚本，都不检查
+func validateJSONFiles(config *Config) error {
+	var invalid []string
+	for _, service := range config.Services {
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Template || endpoint.Type != "" {
+				continue
+			}
+			explicit := ""
+			for k, v := range endpoint.Headers {
+				if http.CanonicalHeaderKey(k) == "Content-Type" {
+					explicit = v
+				}
+			}
+			fullPath := service.BasePath + endpoint.Path
+			check := func(file, contentType string) {
+				if file == "" {
+					return
+				}
+				if explicit != "" {
+					contentType = explicit
+				}
+				if contentType == "" && endpoint.GzipMode != gzipModeRaw {
+					contentType = contentTypeFor(fixtureName(file))
+				}
+				if contentType == "" {
+					contentType = contentTypeFor(file)
+				}
+				if !isJSONContentType(contentType) {
+					return
+				}
+				data, err := readJSONFile(file)
+				if err == nil {
+					var v interface{}
+					err = json.Unmarshal(data, &v)
+				}
+				if err != nil {
+					invalid = append(invalid, fmt.Sprintf("%s (endpoint %s %s in service %s): %v", file, endpoint.Method, fullPath, service.Name, err))
+				}
+			}
+			if len(endpoint.globFiles) > 0 {
+				for _, file := range endpoint.globFiles {
+					check(file, "")
+				}
+			} else {
+				check(endpoint.ResponseFile, "")
+			}
+			for _, rule := range endpoint.Matches {
+				check(rule.ResponseFile, "")
+			}
+			for _, resp := range endpoint.Responses {
+				check(resp.ResponseFile, "")
+			}
+			for _, step := range endpoint.Sequence {
+				check(step.ResponseFile, "")
+			}
+			for _, variant := range endpoint.Variants {
+				check(variant.ResponseFile, variant.MediaType)
+			}
+			if endpoint.Scenario != nil {
+				for _, state := range endpoint.Scenario.States {
+					check(state.ResponseFile, "")
+				}
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid JSON response files:\n  %s", strings.Join(invalid, "\n  "))
+	}
+	return nil
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// readJSONFile 读取响应文件，.gz 文件会透明解压
+func readJSONFile(filePath string) ([]byte, error) {
+	if isGzipFile(filePath) {
+		return gunzipFile(filePath)
+	}
+	return readRawFile(filePath)
+}
+
+// readRawFile 原样读取文件内容，不做解压
+func readRawFile(filePath string) ([]byte, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// openResponseFile 打开响应文件用于流式输出，路径解析方式与 readJSONFile 一致
+func openResponseFile(filePath string) (*os.File, int64, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// contentTypeFor 根据响应文件扩展名推断 Content-Type，内联响应、.json 和未知类型均视为 JSON
+func contentTypeFor(file string) string {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == "" || ext == ".json" {
+		return "application/json"
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/json"
+}
+
+// mockResponse 是一次请求最终选中的响应内容
+type mockResponse struct {
+	file        string
+	body        []byte
+	statusCode  int
+	contentType string
+	// rule 描述响应来自哪条配置，例如 matches[0]，用于 -debug-headers
+	rule string
+}
+
+// responseFiles 返回配置中引用的所有响应文件
+func responseFiles(config *Config) []string {
+	var files []string
+	if config.NotFound != nil && config.NotFound.ResponseFile != "" {
+		files = append(files, config.NotFound.ResponseFile)
+	}
+	for _, service := range config.Services {
+		for _, endpoint := range service.Endpoints {
+			files = append(files, endpointFiles(endpoint)...)
+		}
+	}
+	return files
+}
+
+// mapFiles 对端点引用的每个响应文件路径调用 fn 并写回结果
+func (e *Endpoint) mapFiles(fn func(string) string) {
+	update := func(file *string) {
+		if *file != "" {
+			*file = fn(*file)
+		}
+	}
+	update(&e.ResponseFile)
+	for i := range e.Matches {
+		update(&e.Matches[i].ResponseFile)
+	}
+	for i := range e.Responses {
+		update(&e.Responses[i].ResponseFile)
+	}
+	for i := range e.Sequence {
+		update(&e.Sequence[i].ResponseFile)
+	}
+	for i := range e.Variants {
+		update(&e.Variants[i].ResponseFile)
+	}
+	if e.Scenario != nil {
+		for i := range e.Scenario.States {
+			update(&e.Scenario.States[i].ResponseFile)
+		}
+	}
+	for i := range e.Parts {
+		update(&e.Parts[i].ResponseFile)
+	}
+	update(&e.OverLimitFile)
+}
+
+// resolvePath 把相对路径解析到 dir 下，绝对路径和空路径保持不变
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// resolveFiles 在加载时把相对的响应文件路径拼接到 responseDir 下，responseDir 本身以及 schema
+// 和 exec 命令中的相对路径都相对于配置文件所在目录 configDir，绝对路径保持不变。
+// 这样服务可以从任意工作目录启动，之后读取文件、校验和文件监控使用的都是拼接后的路径
+func (s *Service) resolveFiles(configDir string) {
+	s.ResponseDir = resolvePath(configDir, s.ResponseDir)
+	if s.ResponseDir == "" {
+		s.ResponseDir = configDir
+	}
+	for i := range s.Endpoints {
+		endpoint := &s.Endpoints[i]
+		endpoint.mapFiles(func(file string) string {
+			return resolvePath(s.ResponseDir, file)
+		})
+		endpoint.Schema = resolvePath(configDir, endpoint.Schema)
+		// 只含命令名的 command 仍按 PATH 查找
+		if len(endpoint.Command) > 0 && strings.ContainsRune(endpoint.Command[0], filepath.Separator) {
+			endpoint.Command[0] = resolvePath(configDir, endpoint.Command[0])
+		}
+	}
+}
+
+// endpointFiles 返回单个端点引用的所有响应文件，以及用于校验响应的 schema 文件
+func endpointFiles(endpoint Endpoint) []string {
+	var files []string
+	add := func(file string) {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	if len(endpoint.globFiles) > 0 {
+		files = append(files, endpoint.globFiles...)
+	} else {
+		add(endpoint.ResponseFile)
+	}
+	for _, rule := range endpoint.Matches {
+		add(rule.ResponseFile)
+	}
+	for _, resp := range endpoint.Responses {
+		add(resp.ResponseFile)
+	}
+	for _, step := range endpoint.Sequence {
+		add(step.ResponseFile)
+	}
+	for _, variant := range endpoint.Variants {
+		add(variant.ResponseFile)
+	}
+	if endpoint.Scenario != nil {
+		for _, state := range endpoint.Scenario.States {
+			add(state.ResponseFile)
+		}
+	}
+	for _, part := range endpoint.Parts {
+		add(part.ResponseFile)
+	}
+	add(endpoint.OverLimitFile)
+	add(endpoint.Schema)
+	return files
+}
+
+// needsResponse 判断端点是否必须配置响应内容。echo 端点、从外部文档导入的端点，
+// 以及自己生成响应的端点类型（websocket、redirect、multipart、grpc-web、exec）不需要
+func (e Endpoint) needsResponse() bool {
+	if e.Echo || e.allowEmpty {
+		return false
+	}
+	switch e.Type {
+	case endpointTypeWebSocket, endpointTypeRedirect, endpointTypeMultipart, endpointTypeGRPCWeb, endpointTypeExec:
+		return false
+	}
+	return true
+}
+
+// hasResponse 判断端点是否以任意一种方式配置了响应内容
+func (e Endpoint) hasResponse() bool {
+	hasBody := e.ResponseFile != "" || e.ResponseBody != "" || e.ResponseBodyBase64 != ""
+	hasAlternatives := len(e.Responses) > 0 || len(e.Sequence) > 0 || len(e.Variants) > 0
+	return hasBody || hasAlternatives || e.Scenario != nil || e.Script != ""
+}
+
+const (
+	endpointTypeSSE       = "sse"
+	endpointTypeWebSocket = "websocket"
+)
+
+// handlerContext 是创建端点处理函数时需要的全局配置和命令行选项
+type handlerContext struct {
+	config *Config
+	opts   routerOptions
+}
+
+func (hc handlerContext) streamThreshold() int64 {
+	if hc.config.Server.StreamThreshold > 0 {
+		return hc.config.Server.StreamThreshold
+	}
+	return defaultStreamThreshold
+}
+
+// endpointHandler 根据端点类型创建对应的处理函数，再按配置包装超时控制和响应缓存
+func endpointHandler(hc handlerContext, service Service, endpoint Endpoint) gin.HandlerFunc {
+	var h gin.HandlerFunc
+	switch endpoint.Type {
+	case endpointTypeSSE:
+		h = newSSEHandler(hc, endpoint)
+	case endpointTypeWebSocket:
+		h = newWebSocketHandler(hc, endpoint)
+	case endpointTypeRedirect:
+		h = newRedirectHandler(hc, endpoint)
+	case endpointTypeMultipart:
+		h = newMultipartHandler(hc, endpoint)
+	case endpointTypeGRPCWeb:
+		h = newGRPCWebHandler(hc, endpoint)
+	case endpointTypeExec:
+		h = newExecHandler(hc, endpoint)
+	default:
+		h = newEndpointHandler(hc, service, endpoint)
+	}
+	// exec 端点在取得 worker 之后才开始计时，由 newExecHandler 自己处理 timeout
+	if endpoint.Timeout > 0 && endpoint.Type != endpointTypeExec {
+		h = withTimeout(time.Duration(endpoint.Timeout), endpoint.TimeoutBody, h)
+	}
+	if endpoint.CacheTTL > 0 {
+		cache := newResponseCache(time.Duration(endpoint.CacheTTL))
+		hc.config.caches.register(cache, endpointFiles(endpoint))
+		h = withCache(cache, h)
+	}
+	return h
+}
+
+// debugHeadersMiddleware 在 -debug-headers 模式下标明处理请求的服务和端点
+func debugHeadersMiddleware(service Service, endpoint Endpoint) gin.HandlerFunc {
+	serviceName := service.Name
+	key := routeKey(service, endpoint)
+	return func(c *gin.Context) {
+		c.Header("X-Mock-Service", serviceName)
+		c.Header("X-Mock-Endpoint", key)
+	}
+}
+
+// routeKey 唯一标识一个端点，用于检查重复路由和保存端点级别的状态。
+// 不同 host 或 port 上的同一路由是不同的端点，状态互不影响
+func routeKey(service Service, endpoint Endpoint) string {
+	key := strings.ToUpper(endpoint.Method) + " " + service.BasePath + endpoint.Path
+	if service.Host != "" {
+		key += " on host " + service.Host
+	}
+	if service.Port != 0 {
+		key += " on port " + strconv.Itoa(service.Port)
+	}
+	return key
+}
+
+// routeKeyMatches 判断 routeKey 是否与管理接口给出的 method/path 匹配，参数为空表示不限制。
+// path 只比较路径部分，同一路径在所有 host 和 port 上的端点都会匹配
+func routeKeyMatches(key, method, path string) bool {
+	m, p, _ := strings.Cut(key, " ")
+	p, _, _ = strings.Cut(p, " on ")
+	return (method == "" || strings.EqualFold(m, method)) && (path == "" || p == path)
+}
+
+func newEndpointHandler(hc handlerContext, service Service, endpoint Endpoint) gin.HandlerFunc {
+	defaultResponse := mockResponse{
+		file:       endpoint.ResponseFile,
+		body:       []byte(endpoint.ResponseBody),
+		statusCode: endpoint.StatusCode,
+	}
+	if defaultResponse.statusCode == 0 {
+		defaultResponse.statusCode = 200
+	}
+	// responseBodyBase64 是内联的二进制响应体，已在加载时校验；未指定 Content-Type 时按 application/octet-stream 返回
+	if endpoint.ResponseBodyBase64 != "" {
+		defaultResponse.body, _ = base64.StdEncoding.DecodeString(endpoint.ResponseBodyBase64)
+		if defaultResponse.file == "" {
+			defaultResponse.contentType = "application/octet-stream"
+		}
+	}
+	rules := endpoint.Matches
+	sequence := newSequencePlayer(routeKey(service, endpoint), endpoint.Sequence, endpoint.SequenceMode, defaultResponse.statusCode)
+	weighted := newWeightedPicker(endpoint.Responses, defaultResponse.statusCode)
+	variants := endpoint.Variants
+	scenario := newScenarioPlayer(hc.config, endpoint, defaultResponse.statusCode)
+	files := newFilePicker(endpoint)
+	// 脚本已在加载时编译校验过
+	script, _ := newScriptRunner(service, endpoint, defaultResponse.statusCode)
+	limiter := newCallLimiter(routeKey(service, endpoint), endpoint)
+
+	// 响应选择优先级：调用次数上限 > 脚本 > 匹配规则 > 场景状态 > 序列 > 加权随机 > 内容协商 > 端点默认响应
+	selectResponse := func(c *gin.Context) mockResponse {
+		if limiter != nil && limiter.exceeded() {
+			return limiter.overLimit
+		}
+		if script != nil {
+			resp, ok, err := script.run(c)
+			if err != nil {
+				log.Printf("Script error for %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+				body, _ := json.Marshal(gin.H{"error": "script error: " + err.Error()})
+				return mockResponse{body: body, statusCode: http.StatusInternalServerError, rule: "script"}
+			}
+			if ok {
+				return resp
+			}
+		}
+		for i, rule := range rules {
+			if rule.When.matches(c) {
+				resp := mockResponse{
+					file:       rule.ResponseFile,
+					body:       []byte(rule.ResponseBody),
+					statusCode: rule.StatusCode,
+					rule:       fmt.Sprintf("matches[%d]", i),
+				}
+				if resp.statusCode == 0 {
+					resp.statusCode = defaultResponse.statusCode
+				}
+				return resp
+			}
+		}
+		if scenario != nil {
+			if resp, ok := scenario.next(c); ok {
+				return resp
+			}
+		}
+		if sequence != nil {
+			return sequence.next()
+		}
+		if weighted != nil {
+			return weighted.pick()
+		}
+		if len(variants) > 0 {
+			c.Header("Vary", "Accept")
+			i := negotiateVariant(c.GetHeader("Accept"), variants)
+			v := variants[i]
+			resp := mockResponse{
+				file:        v.ResponseFile,
+				body:        []byte(v.ResponseBody),
+				statusCode:  v.StatusCode,
+				contentType: v.MediaType,
+				rule:        fmt.Sprintf("variants[%d]", i),
+			}
+			if resp.statusCode == 0 {
+				resp.statusCode = defaultResponse.statusCode
+			}
+			return resp
+		}
+		if files != nil {
+			return files.pick(defaultResponse)
+		}
+		return defaultResponse
+	}
+	headers := endpoint.Headers
+	explicitContentType := ""
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) == "Content-Type" {
+			explicitContentType = v
+		}
+	}
+	delay := latency{
+		base:      time.Duration(endpoint.Delay),
+		jitter:    time.Duration(endpoint.Jitter),
+		spike:     time.Duration(endpoint.SpikeDelay),
+		spikeRate: endpoint.SpikeRate,
+	}
+	isTemplate := endpoint.Template
+	reflect := endpoint.Reflect
+	transform, transformErr := prepareTransforms(endpoint.Transform)
+	echo := endpoint.Echo
+	cookies := endpoint.Cookies
+	etag := endpoint.ETag || hc.opts.ETag
+	magicParams := hc.opts.MagicParams
+	debugHeaders := hc.opts.DebugHeaders
+	streamThreshold := hc.streamThreshold()
+	gzipMode := endpoint.GzipMode
+	jsonFormat := hc.opts.JSONFormat
+	faultRate := endpoint.FaultRate
+	faultStatus := endpoint.FaultStatus
+	if faultStatus == 0 {
+		faultStatus = 500
+	}
+	faultBody := []byte(endpoint.FaultBody)
+	if len(faultBody) == 0 {
+		faultBody = []byte(`{"error":"injected fault"}`)
+	}
+
+	return func(c *gin.Context) {
+		if len(c.Params) > 0 {
+			params := make([]string, 0, len(c.Params))
+			for _, p := range c.Params {
+				params = append(params, p.Key+"="+p.Value)
+			}
+			route := c.FullPath()
+			if route == "" {
+				// 正则端点没有 gin 路由模板
+				route = c.Request.URL.Path
+			}
+			log.Printf("Matched %s with path params: %s", route, strings.Join(params, ", "))
+		}
+
+		var magic magicOverrides
+		if magicParams {
+			var err error
+			if magic, err = takeMagicParams(c); err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		// 模拟响应延迟，客户端断开时直接放弃
+		d := delay.sample()
+		if magic.hasDelay {
+			d = magic.delay
+		}
+		if d > 0 {
+			timer := time.NewTimer(d)
+			select {
+			case <-timer.C:
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				c.Abort()
+				return
+			}
+		}
+
+		// 按概率注入故障响应
+		if faultRate > 0 && rng.Float64() < faultRate {
+			c.Data(faultStatus, "application/json", faultBody)
+			return
+		}
+
+		setCookies(c, cookies)
+		if echo && isEchoMethod(c.Request.Method) {
+			for k, v := range headers {
+				if http.CanonicalHeaderKey(k) != "Content-Type" {
+					c.Header(k, v)
+				}
+			}
+			echoRequest(c)
+			return
+		}
+
+		resp := selectResponse(c)
+		if debugHeaders && resp.rule != "" {
+			c.Header("X-Mock-Matched-Rule", resp.rule)
+		}
+		if magic.statusCode != 0 {
+			resp.statusCode = magic.statusCode
+		}
+		contentType := explicitContentType
+		if contentType == "" {
+			contentType = resp.contentType
+		}
+		// .gz 文件按解压后的内容确定类型；客户端接受 gzip 且内容不需要加工时直接返回压缩数据
+		gz := resp.file != "" && isGzipFile(resp.file) && gzipMode != gzipModeRaw
+		passthrough := false
+		if contentType == "" {
+			if gz {
+				contentType = contentTypeFor(fixtureName(resp.file))
+			} else {
+				contentType = contentTypeFor(resp.file)
+			}
+		}
+		// -json 需要重新排版时，JSON 响应必须读入内存处理
+		reformat := (jsonFormat == jsonFormatPretty || jsonFormat == jsonFormatMinify) && isJSONContentType(contentType)
+		if gz && gzipMode != gzipModeDecompress {
+			c.Writer.Header().Add("Vary", "Accept-Encoding")
+			passthrough = acceptsGzip(c.Request) && !isTemplate && len(reflect) == 0 && len(endpoint.Transform) == 0 && !reformat
+			if passthrough {
+				c.Header("Content-Encoding", "gzip")
+			}
+		}
+		decompress := gz && !passthrough
+
+		// 大文件直接从磁盘流式输出，避免整体读入内存；模板、transform、reflect、需要解压和重新排版的文件仍走内存
+		if resp.file != "" && !decompress && !reformat && !isTemplate && len(reflect) == 0 && len(endpoint.Transform) == 0 {
+			if info, err := os.Stat(resp.file); err == nil && info.Size() >= streamThreshold {
+				f, size, err := openResponseFile(resp.file)
+				if err != nil {
+					hc.fileError(c, resp.file, err)
+					return
+				}
+				defer f.Close()
+				if etag && c.Request.Method == http.MethodGet {
+					tag, err := fileETag(f)
+					if err == nil {
+						_, err = f.Seek(0, io.SeekStart)
+					}
+					if err != nil {
+						c.JSON(500, gin.H{"error": err.Error()})
+						return
+					}
+					c.Header("ETag", tag)
+					if etagMatches(c.GetHeader("If-None-Match"), tag) {
+						for k, v := range headers {
+							c.Header(k, v)
+						}
+						c.Status(http.StatusNotModified)
+						return
+					}
+				}
+				c.DataFromReader(resp.statusCode, size, contentType, f, headers)
+				return
+			}
+		}
+
+		data := resp.body
+		if resp.file != "" {
+			var err error
+			if decompress {
+				data, err = readJSONFile(resp.file)
+			} else {
+				data, err = readRawFile(resp.file)
+			}
+			if err != nil {
+				hc.fileError(c, resp.file, err)
+				return
+			}
+		}
+		if isTemplate {
+			rendered, err := renderTemplate(endpoint.Path, data, c, hc.config.partials)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			data = rendered
+		}
+		// transform 只作用于 JSON 响应，其他类型原样返回
+		if len(endpoint.Transform) > 0 && isJSONContentType(contentType) {
+			if transformErr != nil {
+				c.JSON(500, gin.H{"error": transformErr.Error()})
+				return
+			}
+			transformed, err := transformJSON(data, transform)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			data = transformed
+		}
+		if len(reflect) > 0 {
+			reflected, err := reflectFields(c, data, reflect)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			data = reflected
+		}
+		if reformat {
+			data = formatJSON(data, jsonFormat)
+		}
+		// 超时或客户端断开后不再写出响应
+		if c.Request.Context().Err() != nil {
+			c.Abort()
+			return
+		}
+		for k, v := range headers {
+			c.Header(k, v)
+		}
+		// ETag 按最终响应内容计算，文件或模板结果变化时随之变化
+		if etag && c.Request.Method == http.MethodGet {
+			tag := bodyETag(data)
+			c.Header("ETag", tag)
+			if etagMatches(c.GetHeader("If-None-Match"), tag) {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+		c.Data(resp.statusCode, contentType, data)
+	}
 }
 
-type Config struct {
-	Port     int       `yaml:"port"`
-	Services []Service `yaml:"services"`
+// routerOptions 是影响路由构建的命令行选项，重载配置时保持不变
+type routerOptions struct {
+	LogFormat   string
+	Recorder    *recorder
+	Gzip        bool
+	GzipMinSize int
+	Calls       *callRecorder
+	ETag        bool
+	MagicParams bool
+	Metrics     *metrics
+	// DebugHeaders 为 true 时响应带上 X-Mock-* 头，标明由哪条配置产生
+	DebugHeaders bool
+	// RequestIDHeader 为空时不处理请求 ID
+	RequestIDHeader string
+	// MethodNotAllowed 为 true 时，路径存在但方法未配置的请求返回 405 而不是 404
+	MethodNotAllowed bool
+	// JSONFormat 是 JSON 响应的排版方式：passthrough、pretty 或 minify
+	JSONFormat string
+	// ExecPool 限制同时运行的 exec 命令数量，在重载之间共享
+	ExecPool chan struct{}
 }
 
-func loadConfig(filename string) (*Config, error) {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
+// methodAny 表示端点响应所有 HTTP 方法
+const methodAny = "ANY"
 
-	var config Config
-	err = yaml.Unmarshal(data, &config)
-	if err != nil {
-		return nil, err
-	}
+// anyMethods 与 gin 的 Any 注册的方法相同
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodHead,
+	http.MethodOptions, http.MethodDelete, http.MethodConnect, http.MethodTrace,
+}
 
-	return &config, nil
+// anyRoute 是等到具体方法都注册完后再注册的 ANY 端点
+type anyRoute struct {
+	path     string
+	handlers []gin.HandlerFunc
 }
 
-func readJSONFile(filePath string) ([]byte, error) {
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		return nil, err
-	}
+// buildEngine 为一组服务构建 gin 引擎。limiter 是全局限流的令牌桶，多个虚拟主机的引擎共用同一个
+func buildEngine(config *Config, opts routerOptions, limiter *tokenBucket) (engine *gin.Engine, err error) {
+	// gin 在路由冲突（例如同一位置使用不同的参数名）时会 panic，这里转换为错误返回
+	defer func() {
+		if r := recover(); r != nil {
+			engine = nil
+			err = fmt.Errorf("failed to register routes: %v", r)
+		}
+	}()
 
-	data, err := ioutil.ReadFile(absPath)
-	if err != nil {
-		return nil, err
+	var r *gin.Engine
+	switch {
+	case opts.LogFormat == "json":
+		r = gin.New()
+		r.Use(jsonLogger(), gin.Recovery())
+	case opts.RequestIDHeader != "":
+		r = gin.New()
+		r.Use(gin.LoggerWithFormatter(textLogFormatter), gin.Recovery())
+	default:
+		r = gin.Default()
+	}
+	// gin 默认会重定向多余的尾部斜杠，这里按配置显式设置，默认两者都关闭
+	r.RedirectTrailingSlash = config.Server.RedirectTrailingSlash
+	r.RedirectFixedPath = config.Server.CaseInsensitive
+	if config.Server.CaseInsensitive {
+		log.Printf("Case-insensitive routing enabled: mismatched-case paths are redirected to the configured route, path param values keep the case sent by the client")
+	}
+	if opts.RequestIDHeader != "" {
+		r.Use(requestIDMiddleware(opts.RequestIDHeader))
+	}
+	if opts.Metrics != nil {
+		r.Use(metricsMiddleware(opts.Metrics))
+	}
+	// bodyLimit 必须在调用记录、recordDir 等读取请求体的中间件之前，否则这些中间件会先把整个请求体读入内存
+	if config.Server.MaxBodyBytes > 0 {
+		r.Use(bodyLimit(config.Server.MaxBodyBytes))
+	}
+	if opts.Calls != nil {
+		r.Use(callRecorderMiddleware(opts.Calls))
+	}
+	if opts.Gzip {
+		r.Use(gzipMiddleware(opts.GzipMinSize))
+	}
+	// 记录中间件位于 gzip 之后，记录的是未压缩的响应体
+	if dir := config.Server.RecordDir; dir != "" {
+		w, err := getCaptureWriter(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up recordDir: %v", err)
+		}
+		includeBodies := config.Server.RecordBodies == nil || *config.Server.RecordBodies
+		r.Use(captureMiddleware(w, includeBodies))
+	}
+	if limiter != nil {
+		r.Use(rateLimitMiddleware(limiter))
 	}
 
-	return data, nil
-}
+	hc := handlerContext{config: config, opts: opts}
 
-func setupRouter(config *Config) *gin.Engine {
-	r := gin.Default()
+	// 记录需要自动应答预检请求的路径，以及用户已显式配置 OPTIONS 的路径
+	preflightPaths := make(map[string]*CORSConfig)
+	explicitOptions := make(map[string]bool)
+	var pathOrder []string
+	var regexRoutes []regexRoute
+	var anyRoutes []anyRoute
 
 	for _, service := range config.Services {
+		cors := service.CORS
+		if cors == nil {
+			cors = config.Server.CORS
+		}
+
 		for _, endpoint := range service.Endpoints {
 			fullPath := service.BasePath + endpoint.Path
-			responseFile := endpoint.ResponseFile
+			var handlers []gin.HandlerFunc
+			if opts.DebugHeaders {
+				handlers = append(handlers, debugHeadersMiddleware(service, endpoint))
+			}
+			if endpoint.LogBody {
+				handlers = append(handlers, logBodyMiddleware(opts.LogFormat, config.Server.LogBodyMax))
+			}
+			if cors != nil && !endpoint.PathRegex {
+				handlers = append(handlers, corsMiddleware(cors))
+				if _, ok := preflightPaths[fullPath]; !ok {
+					preflightPaths[fullPath] = cors
+					pathOrder = append(pathOrder, fullPath)
+				}
+			}
+			if service.Auth != nil {
+				handlers = append(handlers, authMiddleware(service.Auth, service.Name))
+			}
+			if endpoint.RateLimit != nil {
+				handlers = append(handlers, rateLimitMiddleware(newTokenBucket(endpoint.RateLimit)))
+			}
+			throttle := endpoint.Throttle
+			if throttle == 0 {
+				throttle = config.Server.Throttle
+			}
+			// websocket 连接被接管后不经过 ResponseWriter，限速没有意义
+			if throttle > 0 && endpoint.Type != endpointTypeWebSocket {
+				handlers = append(handlers, throttleMiddleware(throttle))
+			}
+			handlers = append(handlers, endpointHandler(hc, service, endpoint))
+
+			if endpoint.PathRegex {
+				re, err := compilePathRegex(service.BasePath, endpoint.Path)
+				if err != nil {
+					return nil, err
+				}
+				if cors != nil {
+					handlers = append([]gin.HandlerFunc{corsMiddleware(cors)}, handlers...)
+				}
+				regexRoutes = append(regexRoutes, regexRoute{method: endpoint.Method, path: fullPath, re: re, handlers: handlers})
+				continue
+			}
 
-			switch endpoint.Method {
+			switch strings.ToUpper(endpoint.Method) {
 			case "GET":
-				r.GET(fullPath, func(c *gin.Context) {
-					data, err := readJSONFile(responseFile)
-					if err != nil {
-						c.JSON(500, gin.H{"error": err.Error()})
-						return
-					}
-					c.Data(200, "application/json", data)
-				})
+				r.GET(fullPath, handlers...)
 			case "POST":
-				r.POST(fullPath, func(c *gin.Context) {
-					data, err := readJSONFile(responseFile)
-					if err != nil {
-						c.JSON(500, gin.H{"error": err.Error()})
-						return
-					}
-					c.Data(200, "application/json", data)
-				})
+				r.POST(fullPath, handlers...)
+			case "PUT":
+				r.PUT(fullPath, handlers...)
+			case "DELETE":
+				r.DELETE(fullPath, handlers...)
+			case "PATCH":
+				r.PATCH(fullPath, handlers...)
+			case "HEAD":
+				r.HEAD(fullPath, handlers...)
+			case "OPTIONS":
+				r.OPTIONS(fullPath, handlers...)
+				explicitOptions[fullPath] = true
+			case methodAny:
+				anyRoutes = append(anyRoutes, anyRoute{path: fullPath, handlers: handlers})
+			default:
+				log.Printf("Warning: unsupported method %q for %s in service %s, endpoint skipped", endpoint.Method, fullPath, service.Name)
+			}
+		}
+	}
+
+	for _, fullPath := range pathOrder {
+		if explicitOptions[fullPath] {
+			continue
+		}
+		r.OPTIONS(fullPath, corsMiddleware(preflightPaths[fullPath]), preflightHandler)
+	}
+
+	// ANY 端点只注册同一路径上还没有被具体方法占用的方法，因此具体方法总是优先
+	if len(anyRoutes) > 0 {
+		taken := make(map[string]bool)
+		for _, route := range r.Routes() {
+			taken[route.Method+" "+route.Path] = true
+		}
+		for _, route := range anyRoutes {
+			var methods []string
+			for _, method := range anyMethods {
+				if taken[method+" "+route.path] {
+					continue
+				}
+				r.Handle(method, route.path, route.handlers...)
+				methods = append(methods, method)
 			}
+			log.Printf("Registered ANY route %s for methods %s", route.path, strings.Join(methods, ", "))
+		}
+	}
+
+	// 静态目录由 http.Dir 提供，请求路径会被限制在目录内
+	for _, m := range config.Static {
+		r.Static(m.Prefix, m.Dir)
+	}
+
+	// 配置了 upstream 时未匹配的请求转发到上游，否则使用 notFound 响应
+	var noRoute gin.HandlerFunc
+	if config.Upstream != "" {
+		target, err := parseUpstream(config.Upstream)
+		if err != nil {
+			return nil, err
+		}
+		noRoute = newProxyHandler(target, opts.Recorder)
+	} else if config.NotFound != nil {
+		noRoute = newEndpointHandler(hc, Service{}, config.NotFound.endpoint())
+	}
+	// 正则端点在所有普通路由之后、未匹配处理之前尝试
+	if len(regexRoutes) > 0 {
+		fallback := noRoute
+		if opts.MethodNotAllowed {
+			fallback = regexMethodNotAllowed(regexRoutes, noRoute)
+		}
+		noRoute = regexDispatcher(regexRoutes, fallback)
+	}
+	// 路径存在但方法不对时返回 405，gin 会写出列有已配置方法的 Allow 头
+	if opts.MethodNotAllowed {
+		r.HandleMethodNotAllowed = true
+		if len(regexRoutes) > 0 {
+			r.NoMethod(regexNoMethod(regexRoutes))
 		}
 	}
+	if noRoute != nil {
+		r.NoRoute(noRoute)
+	}
+
+	return r, nil
+}
+
+// activeRouter 是同一次加载得到的配置和引擎，二者总是一起替换
+type activeRouter struct {
+	config *Config
+	engine http.Handler
+}
+
+// routerHandler 将请求转发给当前生效的 gin 引擎，重载配置时整体替换引擎。
+// 新引擎完全构建好后才通过原子指针替换，每个请求从头到尾只使用它开始时读到的那一个引擎，
+// 正在处理的请求不受替换影响。健康检查和管理接口由它直接处理，不受用户配置和重载影响
+type routerHandler struct {
+	active     atomic.Pointer[activeRouter]
+	admin      *gin.Engine
+	calls      *callRecorder
+	reload     func() error
+	healthPath string
+	livePath   string
+	readyPath  string
+	// prefixBuiltins 为 true 时内置接口也挂在 server.basePath 之下
+	prefixBuiltins bool
+	metrics        http.Handler
+	draining       atomic.Bool
+	// reloadErr 是最近一次重载失败的原因，重载成功后清空
+	reloadErr atomic.Pointer[string]
+}
+
+func (h *routerHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if builtin := h.builtinRequest(req); builtin != nil && h.serveBuiltin(w, builtin) {
+		return
+	}
+	h.active.Load().engine.ServeHTTP(w, req)
+}
 
+// builtinRequest 返回用于匹配内置接口的请求。-prefix-builtins 开启且配置了 server.basePath 时，
+// 内置接口只在该前缀下提供，返回去掉前缀后的请求；不带前缀的请求返回 nil，全部交给用户路由
+func (h *routerHandler) builtinRequest(req *http.Request) *http.Request {
+	if !h.prefixBuiltins {
+		return req
+	}
+	prefix := h.activeConfig().Server.BasePath
+	if prefix == "" {
+		return req
+	}
+	rest, ok := strings.CutPrefix(req.URL.Path, prefix)
+	if !ok || !strings.HasPrefix(rest, "/") {
+		return nil
+	}
+	// 与 http.StripPrefix 相同，浅拷贝请求后替换路径
+	r := new(http.Request)
+	*r = *req
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+	r.URL.Path = rest
+	r.URL.RawPath = ""
 	return r
 }
 
+// serveBuiltin 处理健康检查、指标和管理接口，返回 false 表示请求不属于内置接口
+func (h *routerHandler) serveBuiltin(w http.ResponseWriter, req *http.Request) bool {
+	isRead := req.Method == http.MethodGet || req.Method == http.MethodHead
+	switch {
+	case h.healthPath != "" && req.URL.Path == h.healthPath && isRead:
+		h.serveHealth(w)
+	case h.livePath != "" && req.URL.Path == h.livePath && isRead:
+		writeStatusJSON(w, http.StatusOK, gin.H{"status": "alive"})
+	case h.readyPath != "" && req.URL.Path == h.readyPath && isRead:
+		h.serveReady(w)
+	case h.metrics != nil && req.URL.Path == metricsPath:
+		h.metrics.ServeHTTP(w, req)
+	case h.admin != nil && (strings.HasPrefix(req.URL.Path, adminPrefix) || req.URL.Path == versionPath):
+		h.admin.ServeHTTP(w, req)
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *routerHandler) serveHealth(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"draining"}`))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// serveReady 在排空连接或最近一次重载失败时返回 503。重载失败时旧配置仍在服务，
+// 进程保持存活（/__live 仍为 200），只是让编排系统把它从负载均衡中摘除
+func (h *routerHandler) serveReady(w http.ResponseWriter) {
+	if h.draining.Load() {
+		writeStatusJSON(w, http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	if msg := h.reloadErr.Load(); msg != nil {
+		writeStatusJSON(w, http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": *msg})
+		return
+	}
+	writeStatusJSON(w, http.StatusOK, gin.H{"status": "ready"})
+}
+
+func (h *routerHandler) setReloadError(err error) {
+	if err == nil {
+		h.reloadErr.Store(nil)
+		return
+	}
+	msg := err.Error()
+	h.reloadErr.Store(&msg)
+}
+
+func writeStatusJSON(w http.ResponseWriter, status int, body gin.H) {
+	data, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// setActive 同时替换生效的配置和引擎，请求只会看到同一版本的两者
+func (h *routerHandler) setActive(config *Config, engine http.Handler) {
+	h.active.Store(&activeRouter{config: config, engine: engine})
+}
+
+func (h *routerHandler) activeConfig() *Config {
+	return h.active.Load().config
+}
+
+// resolveListenAddr 按 -addr 参数 > server 配置 > 顶层 port > 默认 :8080 的优先级确定监听地址
+func resolveListenAddr(flagAddr string, config *Config) (string, error) {
+	addr := flagAddr
+	if addr == "" {
+		port := config.Server.Port
+		if port == 0 {
+			port = config.Port
+		}
+		if port == 0 {
+			port = defaultPort
+		}
+		addr = net.JoinHostPort(config.Server.Address, strconv.Itoa(port))
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %v", addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return "", fmt.Errorf("invalid port in listen address %q", addr)
+	}
+	return addr, nil
+}
+
 func getLocalIP() (string, error) {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
@@ -109,35 +1884,191 @@ func getLocalIP() (string, error) {
 }
 
 func main() {
-	config, err := loadConfig("./config.yaml")
+	configPath := flag.String("config", "./config.yaml", "path to the mock config file")
+	addrFlag := flag.String("addr", "", "listen address, e.g. :8080 or 127.0.0.1:9090 (overrides config)")
+	logFormat := flag.String("log-format", "text", "access log format: text or json")
+	openAPIFile := flag.String("openapi", "", "OpenAPI 3 spec to generate mock endpoints from, merged with -config")
+	postmanFile := flag.String("postman", "", "Postman v2.1 collection to generate mock endpoints from, merged with -config")
+	harFile := flag.String("har", "", "HAR capture to replay as mock endpoints, merged with -config")
+	strictEnv := flag.Bool("strict-env", false, "fail when the config references an undefined environment variable")
+	strictJSON := flag.Bool("strict", false, "fail when a response file served as JSON is not valid JSON")
+	record := flag.Bool("record", false, "record proxied upstream responses as response files")
+	recordDir := flag.String("record-dir", "./recorded", "directory for recorded response files and recorded.yaml")
+	recordOverwrite := flag.Bool("record-overwrite", false, "overwrite existing recorded response files")
+	gzipEnabled := flag.Bool("gzip", false, "gzip responses for clients that send Accept-Encoding: gzip")
+	gzipMinSize := flag.Int("gzip-min-size", 1024, "minimum response size in bytes before gzip is applied")
+	seed := flag.Int64("seed", 0, "seed for random response selection (0 uses the current time)")
+	adminEnabled := flag.Bool("admin", false, "enable the /__admin/ API")
+	maxCalls := flag.Int("max-recorded-calls", 1000, "maximum number of calls kept for GET /__admin/requests")
+	etagEnabled := flag.Bool("etag", false, "send ETag on GET responses and answer matching If-None-Match with 304")
+	magicParams := flag.Bool("magic-params", false, "let requests override delay and status with __delay and __status query parameters")
+	metricsEnabled := flag.Bool("metrics", false, "expose Prometheus metrics at "+metricsPath)
+	requestID := flag.Bool("request-id", true, "read or generate a request ID, echo it in the response and include it in access logs")
+	requestIDHeader := flag.String("request-id-header", "X-Request-Id", "header carrying the request ID")
+	check := flag.Bool("check", false, "validate the config, including JSON response files, and exit without starting the server")
+	h2cEnabled := flag.Bool("h2c", false, "accept HTTP/2 without TLS (h2c)")
+	methodNotAllowed := flag.Bool("method-not-allowed", false, "return 405 with an Allow header when the path is configured but the method is not, instead of 404")
+	debugHeaders := flag.Bool("debug-headers", false, "add X-Mock-Service, X-Mock-Endpoint and X-Mock-Matched-Rule headers identifying the config entry that produced each response")
+	healthPath := flag.String("health-path", "/__health", "path of the built-in health check endpoint, empty to disable")
+	livePath := flag.String("live-path", "/__live", "path of the liveness endpoint, always 200 while the process runs; empty to disable")
+	prefixBuiltins := flag.Bool("prefix-builtins", false, "serve health, metrics and admin endpoints under server.basePath instead of at the root")
+	readyPath := flag.String("ready-path", "/__ready", "path of the readiness endpoint, 503 while the last reload failed or during shutdown; empty to disable")
+	allowExec := flag.Bool("allow-exec", false, "allow endpoints of type exec, which run commands from the config")
+	execWorkers := flag.Int("exec-workers", defaultExecWorkers, "maximum number of exec endpoint commands running at the same time")
+	jsonFormat := flag.String("json", jsonFormatPassthrough, "JSON response formatting: pretty re-indents, minify strips whitespace, passthrough serves files verbatim")
+	showVersion := flag.Bool("version", false, "print version information and exit")
+	flag.BoolVar(showVersion, "v", false, "shorthand for -version")
+	flag.Parse()
+	if *showVersion {
+		fmt.Println(currentBuildInfo())
+		return
+	}
+	opts := loadOptions{StrictEnv: *strictEnv, StrictJSON: *strictJSON, OpenAPIFile: *openAPIFile, PostmanFile: *postmanFile, HARFile: *harFile, AllowExec: *allowExec}
+	if *execWorkers < 1 {
+		log.Fatalf("Invalid -exec-workers %d, expected at least 1", *execWorkers)
+	}
+	if *seed != 0 {
+		rng.seed(*seed)
+	}
+	if *logFormat != "text" && *logFormat != "json" {
+		log.Fatalf("Invalid -log-format %q, expected text or json", *logFormat)
+	}
+	if err := validateJSONFormat(*jsonFormat); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateMaxRecordedCalls(*maxCalls); err != nil {
+		log.Fatal(err)
+	}
+	if *check {
+		os.Exit(runCheck(*configPath, opts))
+	}
+	routerOpts := routerOptions{LogFormat: *logFormat, Gzip: *gzipEnabled, GzipMinSize: *gzipMinSize, ETag: *etagEnabled, MagicParams: *magicParams, DebugHeaders: *debugHeaders, MethodNotAllowed: *methodNotAllowed, JSONFormat: *jsonFormat, ExecPool: make(chan struct{}, *execWorkers)}
+	if *adminEnabled {
+		routerOpts.Calls = newCallRecorder(*maxCalls)
+	}
+	if *metricsEnabled {
+		routerOpts.Metrics = newMetrics()
+	}
+	if *requestID {
+		routerOpts.RequestIDHeader = *requestIDHeader
+	}
+	if *record {
+		rec, err := newRecorder(*recordDir, *recordOverwrite)
+		if err != nil {
+			log.Fatalf("Failed to set up recorder: %v", err)
+		}
+		routerOpts.Recorder = rec
+	}
+
+	config, err := loadConfig(*configPath, opts)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	var configLock sync.RWMutex
-	r := setupRouter(config)
+	addr, err := resolveListenAddr(*addrFlag, config)
+	if err != nil {
+		log.Fatalf("Failed to resolve listen address: %v", err)
+	}
+	// 配置了 port 的服务各自使用一个监听器，端口集合在运行期间不变
+	ports := servicePorts(config)
+	portAddrs, err := checkPorts(config, addr)
+	if err != nil {
+		log.Fatalf("Invalid service ports: %v", err)
+	}
+
+	engine, err := setupRouter(config, routerOpts)
+	if err != nil {
+		log.Fatalf("Failed to set up routes: %v", err)
+	}
+	handler := &routerHandler{healthPath: *healthPath, livePath: *livePath, readyPath: *readyPath, prefixBuiltins: *prefixBuiltins}
+	handler.setActive(config, engine)
+	if *adminEnabled {
+		handler.calls = routerOpts.Calls
+		handler.admin = newAdminRouter(handler)
+	}
+	if routerOpts.Metrics != nil {
+		handler.metrics = routerOpts.Metrics.handler()
+	}
 
 	// 创建文件监控
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		log.Fatalf("Failed to create watcher: %v", err)
 	}
-	defer watcher.Close()
 
-	// 添加配置文件到监控
-	err = watcher.Add("./config.yaml")
-	if err != nil {
-		log.Printf("Failed to watch config file: %v", err)
+	// 监控配置文件和所有响应文件。配置为目录时直接监控目录，
+	// 目录监控会同时报告其中文件的修改和新增
+	watches := newWatchSet(watcher)
+	// reloadPaths 是变化后需要重新加载配置的文件，其余监控的都是响应文件
+	reloadPaths := func(config *Config) []string {
+		paths := []string{*configPath}
+		// 配置目录中的文件已由目录监控覆盖，这里只补上目录外 include 的文件
+		for _, file := range config.files {
+			if filepath.Dir(file) != filepath.Clean(*configPath) {
+				paths = append(paths, file)
+			}
+		}
+		for _, file := range []string{opts.OpenAPIFile, opts.PostmanFile, opts.HARFile} {
+			if file != "" {
+				paths = append(paths, file)
+			}
+		}
+		return append(paths, config.partialFiles...)
 	}
-
-	// 添加所有JSON响应文件到监控
-	for _, service := range config.Services {
-		for _, endpoint := range service.Endpoints {
-			err = watcher.Add(endpoint.ResponseFile)
-			if err != nil {
-				log.Printf("Failed to watch response file %s: %v", endpoint.ResponseFile, err)
+	watchPaths := func(config *Config) []string {
+		return append(reloadPaths(config), responseFiles(config)...)
+	}
+	// responseOnly 判断 path 是否只是响应文件。响应文件在每次请求时读取，变化时不必重载配置，
+	// 清空引用它的端点缓存即可
+	responseOnly := func(path string) bool {
+		config := handler.active.Load().config
+		path = filepath.Clean(path)
+		for _, p := range reloadPaths(config) {
+			if filepath.Clean(p) == path {
+				return false
+			}
+		}
+		for _, p := range responseFiles(config) {
+			if filepath.Clean(p) == path {
+				return true
 			}
 		}
+		return false
+	}
+	invalidate := func(path string) {
+		n := handler.active.Load().config.caches.invalidate(path)
+		log.Printf("Response file modified: %s, %d endpoint caches invalidated", path, n)
+	}
+	watches.sync(watchPaths(config))
+
+	// 文件监控和管理接口共用同一个重载流程，互斥执行
+	var reloadLock sync.Mutex
+	reload := func() error {
+		reloadLock.Lock()
+		defer reloadLock.Unlock()
+
+		newConfig, err := loadConfig(*configPath, opts)
+		if err != nil {
+			return err
+		}
+		if err := checkPortsUnchanged(ports, newConfig); err != nil {
+			return err
+		}
+
+		// 根据新配置重建路由并替换当前引擎，失败时保留旧配置
+		engine, err := setupRouter(newConfig, routerOpts)
+		if err != nil {
+			return err
+		}
+		handler.setActive(newConfig, engine)
+		watches.sync(watchPaths(newConfig))
+		log.Printf("Config reloaded, routes rebuilt")
+		return nil
+	}
+	handler.reload = func() error {
+		err := reload()
+		handler.setReloadError(err)
+		return err
 	}
 
 	// 启动文件监控协程
@@ -148,21 +2079,34 @@ func main() {
 				if !ok {
 					return
 				}
-				if event.Op&fsnotify.Write == fsnotify.Write {
+				// 编辑器常以重命名方式保存文件，旧文件的监控会随之失效，需要等新文件出现后重新监控
+				if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+					if watches.removed(event.Name) {
+						if responseOnly(event.Name) {
+							// 不重载配置时 sync 不会执行，需要自己重新监控新文件
+							go watches.waitReplaced(event.Name, func() error {
+								watches.rewatch(event.Name)
+								invalidate(event.Name)
+								return nil
+							})
+						} else {
+							go watches.waitReplaced(event.Name, handler.reload)
+						}
+						continue
+					}
+				}
+				if event.Op&fsnotify.Write == fsnotify.Write && responseOnly(event.Name) {
+					invalidate(event.Name)
+					continue
+				}
+				// 监控目录时新增的配置文件会产生 Create 事件，删除配置文件同样需要重载
+				if event.Op&fsnotify.Write == fsnotify.Write || (event.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 && isConfigFile(event.Name)) {
 					log.Printf("File modified: %s", event.Name)
 
 					// 重新加载配置
-					configLock.Lock()
-					newConfig, err := loadConfig("./config.yaml")
-					if err != nil {
+					if err := handler.reload(); err != nil {
 						log.Printf("Failed to reload config: %v", err)
-						configLock.Unlock()
-						continue
 					}
-
-					// 更新路由
-					*config = *newConfig
-					configLock.Unlock()
 				}
 			case err, ok := <-watcher.Errors:
 				if !ok {
@@ -177,6 +2121,96 @@ func main() {
 	if err != nil {
 		log.Printf("Failed to get local IP: %v", err)
 	}
-	fmt.Printf("Starting mock server on http://%s:%d\n", ip, config.Port)
-	r.Run(fmt.Sprintf(":%d", config.Port))
+	_, port, _ := net.SplitHostPort(addr)
+	if len(ports) == 0 {
+		log.Printf("Listening on %s", addr)
+	} else {
+		log.Printf("Listening on %s for services: %s", addr, serviceNames(servicesOnPort(config, 0)))
+		for _, p := range ports {
+			log.Printf("Listening on %s for services: %s", portAddrs[p], serviceNames(servicesOnPort(config, p)))
+		}
+	}
+	tlsConfig := config.Server.TLS
+	scheme := "http"
+	if tlsConfig.enabled() {
+		scheme = "https"
+	}
+	fmt.Printf("Starting mock server on %s://%s:%s\n", scheme, ip, port)
+	var serverHandler http.Handler = handler
+	if *h2cEnabled {
+		// TLS 下 net/http 已经通过 ALPN 支持 HTTP/2，h2c 只用于明文连接
+		if tlsConfig.enabled() {
+			log.Printf("Warning: -h2c is ignored when TLS is enabled")
+		} else {
+			serverHandler = h2c.NewHandler(handler, &http2.Server{})
+			log.Printf("HTTP/2 cleartext (h2c) enabled")
+		}
+	}
+	// 所有监听器共用同一个 handler、超时和 TLS 设置，由 handler 按到达端口分派
+	servers := []*http.Server{{Addr: addr}}
+	for _, p := range ports {
+		servers = append(servers, &http.Server{Addr: portAddrs[p]})
+	}
+	for _, server := range servers {
+		server.Handler = serverHandler
+		server.ReadTimeout = orDefault(config.Server.ReadTimeout, defaultReadTimeout)
+		server.WriteTimeout = orDefault(config.Server.WriteTimeout, 0)
+		server.IdleTimeout = orDefault(config.Server.IdleTimeout, defaultIdleTimeout)
+	}
+	log.Printf("Server timeouts: read %v, write %v, idle %v (0 means no limit)", servers[0].ReadTimeout, servers[0].WriteTimeout, servers[0].IdleTimeout)
+	if tlsConfig.enabled() {
+		serverTLS, err := tlsConfig.serverTLSConfig()
+		if err != nil {
+			log.Fatalf("Failed to load client CA: %v", err)
+		}
+		if serverTLS != nil {
+			log.Printf("Client certificate verification enabled (required: %v)", tlsConfig.RequireClientCert)
+			for _, server := range servers {
+				server.TLSConfig = serverTLS.Clone()
+			}
+		}
+	}
+
+	// 收到 SIGINT/SIGTERM 后停止接收新连接，等待进行中的请求处理完毕
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 任一监听器启动失败或意外退出都会停止整个进程
+	serverErr := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			var err error
+			if tlsConfig.enabled() {
+				err = server.ListenAndServeTLS(tlsConfig.CertFile, tlsConfig.KeyFile)
+			} else {
+				err = server.ListenAndServe()
+			}
+			serverErr <- fmt.Errorf("%s: %v", server.Addr, err)
+		}(server)
+	}
+
+	select {
+	case err := <-serverErr:
+		watcher.Close()
+		log.Fatalf("Server stopped: %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, draining connections")
+	handler.draining.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Graceful shutdown of %s failed: %v", server.Addr, err)
+			}
+		}(server)
+	}
+	wg.Wait()
+	watcher.Close()
+	log.Printf("Server stopped")
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testServer 按 main 的方式加载配置并构建 routerHandler，便于用 httptest 测试完整的请求链
+type testServer struct {
+	t        *testing.T
+	dir      string
+	opts     routerOptions
+	loadOpts loadOptions
+	handler  *routerHandler
+}
+
+// writeTestFiles 把 files 写入临时目录，内容中的 @DIR@ 替换为该目录
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.ReplaceAll(content, "@DIR@", dir)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// newTestServer 加载 files 中的 config.yaml。opts.Calls 不为 nil 时同时启用管理接口
+func newTestServer(t *testing.T, files map[string]string, opts routerOptions) *testServer {
+	t.Helper()
+	return newTestServerWithLoad(t, files, opts, loadOptions{})
+}
+
+// newTestServerWithLoad 与 newTestServer 相同，但按 loadOpts 加载配置，loadOpts 中的文件名以 @DIR@ 开头时替换为临时目录
+func newTestServerWithLoad(t *testing.T, files map[string]string, opts routerOptions, loadOpts loadOptions) *testServer {
+	t.Helper()
+	resetState()
+	s := &testServer{t: t, dir: writeTestFiles(t, files), opts: opts, loadOpts: loadOpts}
+	for _, file := range []*string{&s.loadOpts.HARFile, &s.loadOpts.OpenAPIFile, &s.loadOpts.PostmanFile} {
+		*file = strings.ReplaceAll(*file, "@DIR@", s.dir)
+	}
+	s.handler = &routerHandler{}
+	if opts.Calls != nil {
+		s.handler.calls = opts.Calls
+		s.handler.admin = newAdminRouter(s.handler)
+	}
+	s.handler.reload = s.reload
+	if err := s.reload(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	return s
+}
+
+// resetState 清空跨重载保留的全局状态，避免测试之间互相影响
+func resetState() {
+	sequences.mu.Lock()
+	sequences.states = make(map[string]*sequenceState)
+	sequences.mu.Unlock()
+	callCounters.mu.Lock()
+	callCounters.counts = make(map[string]*atomic.Int64)
+	callCounters.mu.Unlock()
+	scenarios.reset("", "", true)
+}
+
+func (s *testServer) reload() error {
+	config, err := loadConfig(filepath.Join(s.dir, "config.yaml"), s.loadOpts)
+	if err != nil {
+		return err
+	}
+	engine, err := setupRouter(config, s.opts)
+	if err != nil {
+		return err
+	}
+	s.handler.setActive(config, engine)
+	return nil
+}
+
+// write 修改临时目录中的文件
+func (s *testServer) write(name, content string) {
+	s.t.Helper()
+	if err := os.WriteFile(filepath.Join(s.dir, name), []byte(content), 0o644); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// do 发送请求，headers 按 key, value 成对给出，Host 头会设置到 req.Host
+func (s *testServer) do(method, target string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, target, body)
+	for i := 0; i+1 < len(headers); i += 2 {
+		if http.CanonicalHeaderKey(headers[i]) == "Host" {
+			req.Host = headers[i+1]
+			continue
+		}
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (s *testServer) get(target string, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	return s.do(http.MethodGet, target, nil, headers...)
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, status, w.Body.String())
+	}
+}
+
+func expectBody(t *testing.T, w *httptest.ResponseRecorder, body string) {
+	t.Helper()
+	if got := strings.TrimSpace(w.Body.String()); got != body {
+		t.Fatalf("body = %q, want %q", got, body)
+	}
+}
+
+// chunkedBody 返回没有 Content-Length 的请求体，httptest 会按分块请求处理
+func chunkedBody(n int) io.Reader {
+	return io.MultiReader(strings.NewReader(strings.Repeat("x", n)))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MatchRule 根据请求内容选择响应，按配置顺序匹配，第一个命中的规则生效
+type MatchRule struct {
+	When         MatchCondition `yaml:"when" json:"when" toml:"when"`
+	ResponseFile string         `yaml:"responseFile" json:"responseFile" toml:"responseFile"`
+	ResponseBody string         `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+	StatusCode   int            `yaml:"statusCode" json:"statusCode" toml:"statusCode"`
+}
+
+// MatchCondition 中的所有条件都满足时规则才算命中。
+// Body 的键是以点分隔的 JSON 字段路径，例如 user.tier；
+// Headers 的键不区分大小写，值为空时只要求请求带有该头
+type MatchCondition struct {
+	Query   map[string]string `yaml:"query" json:"query" toml:"query"`
+	Headers map[string]string `yaml:"headers" json:"headers" toml:"headers"`
+	Body    map[string]string `yaml:"body" json:"body" toml:"body"`
+}
+
+func parseJSONBody(c *gin.Context) interface{} {
+	body, err := readRequestBody(c)
+	if err != nil || len(body) == 0 {
+		return nil
+	}
+	var parsed interface{}
+	if json.Unmarshal(body, &parsed) != nil {
+		return nil
+	}
+	return parsed
+}
+
+// parseJSONBodyNumbers 与 parseJSONBody 相同，但数字解码为 json.Number，保留请求中原样的数字文本
+func parseJSONBodyNumbers(c *gin.Context) interface{} {
+	body, err := readRequestBody(c)
+	if err != nil || len(body) == 0 {
+		return nil
+	}
+	var parsed interface{}
+	if unmarshalJSONNumbers(body, &parsed) != nil {
+		return nil
+	}
+	return parsed
+}
+
+func (m MatchCondition) matches(c *gin.Context) bool {
+	query := c.Request.URL.Query()
+	for k, v := range m.Query {
+		if values, ok := query[k]; !ok || len(values) == 0 || values[0] != v {
+			return false
+		}
+	}
+
+	for k, v := range m.Headers {
+		values, ok := c.Request.Header[http.CanonicalHeaderKey(k)]
+		if !ok || (v != "" && values[0] != v) {
+			return false
+		}
+	}
+
+	if len(m.Body) > 0 {
+		// 按 json.Number 解码，数字按原文比较，不会被 fmt 格式化成 1e+06 这样的形式
+		body := parseJSONBodyNumbers(c)
+		for field, v := range m.Body {
+			value, ok := lookupField(body, field)
+			if !ok || !matchesBodyValue(value, v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchesBodyValue 比较请求体字段与条件中的字符串。数字先按原文比较，
+// 不同写法（例如 1000000、1e6 和 1000000.0）按数值比较，两边都是整数时用 int64 比较以免丢失精度
+func matchesBodyValue(value interface{}, want string) bool {
+	n, ok := value.(json.Number)
+	if !ok {
+		return fmt.Sprint(value) == want
+	}
+	if n.String() == want {
+		return true
+	}
+	if a, err := n.Int64(); err == nil {
+		if b, err := strconv.ParseInt(want, 10, 64); err == nil {
+			return a == b
+		}
+	}
+	a, err := n.Float64()
+	if err != nil {
+		return false
+	}
+	b, err := strconv.ParseFloat(want, 64)
+	return err == nil && a == b
+}
+
+func lookupField(data interface{}, field string) (interface{}, bool) {
+	current := data
+	for _, key := range strings.Split(field, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
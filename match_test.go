@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const matchConfig = `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /users
+        method: [GET, POST]
+        responseBody: default
+        matches:
+          - when:
+              query:
+                role: admin
+            responseBody: admin
+          - when:
+              body:
+                user.tier: gold
+            statusCode: 201
+            responseBody: gold
+`
+
+func TestMatchByQuery(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": matchConfig}, routerOptions{})
+	expectBody(t, s.get("/users?role=admin"), "admin")
+	expectBody(t, s.get("/users?role=guest"), "default")
+}
+
+func TestMatchByBodyField(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": matchConfig}, routerOptions{})
+	w := s.do("POST", "/users", strings.NewReader(`{"user":{"tier":"gold"}}`), "Content-Type", "application/json")
+	expectStatus(t, w, 201)
+	expectBody(t, w, "gold")
+	expectBody(t, s.do("POST", "/users", strings.NewReader(`{"user":{"tier":"silver"}}`)), "default")
+}
+
+func TestMatchFallsBackToDefault(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": matchConfig}, routerOptions{})
+	w := s.get("/users")
+	expectStatus(t, w, 200)
+	expectBody(t, w, "default")
+	expectBody(t, s.do("POST", "/users", strings.NewReader("not json")), "default")
+}
+
+const headerMatchConfig = `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /tenant
+        method: GET
+        responseBody: default
+        matches:
+          - when:
+              headers:
+                x-tenant: acme
+            responseBody: acme
+          - when:
+              headers:
+                X-Debug: ""
+            responseBody: debug
+`
+
+func TestMatchByHeader(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": headerMatchConfig}, routerOptions{})
+	// 配置中的头名不区分大小写，值按精确匹配
+	expectBody(t, s.get("/tenant", "X-Tenant", "acme"), "acme")
+	expectBody(t, s.get("/tenant", "X-Tenant", "ACME"), "default")
+	// 值为空时只检查请求是否带有该头
+	expectBody(t, s.get("/tenant", "x-debug", "anything"), "debug")
+	expectBody(t, s.get("/tenant"), "default")
+}
+
+const numericMatchConfig = `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /pay
+        method: POST
+        responseBody: default
+        matches:
+          - when:
+              body:
+                amount: "1000000"
+            responseBody: large
+          - when:
+              body:
+                order.id: "9007199254740993"
+            responseBody: order
+          - when:
+              body:
+                rate: "0.5"
+            responseBody: half
+`
+
+func TestMatchByNumericBodyField(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": numericMatchConfig}, routerOptions{})
+	cases := map[string]string{
+		`{"amount": 1000000}`:                 "large",
+		`{"amount": 1e6}`:                     "large",
+		`{"amount": 1000000.0}`:               "large",
+		`{"amount": 1000001}`:                 "default",
+		`{"order": {"id": 9007199254740993}}`: "order",
+		// 超过 float64 精度的相邻整数不能被当成同一个值
+		`{"order": {"id": 9007199254740992}}`: "default",
+		`{"rate": 0.50}`:                      "half",
+	}
+	for body, want := range cases {
+		w := s.do("POST", "/pay", strings.NewReader(body), "Content-Type", "application/json")
+		if got := strings.TrimSpace(w.Body.String()); got != want {
+			t.Errorf("body %s matched %q, want %q", body, got, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// MethodList 对应端点配置中的 method 字段，可以写成单个方法、
+// 逗号分隔的字符串（"GET, HEAD"）或列表（[GET, HEAD]）。
+// 加载配置时会按方法展开端点，之后的代码只使用 Endpoint.Method
+type MethodList []string
+
+func splitMethods(s string) MethodList {
+	var methods MethodList
+	for _, m := range strings.Split(s, ",") {
+		methods = append(methods, strings.TrimSpace(m))
+	}
+	return methods
+}
+
+func (m *MethodList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*m = list
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	*m = splitMethods(s)
+	return nil
+}
+
+func (m *MethodList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*m = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("method must be a string or a list of strings")
+	}
+	*m = splitMethods(s)
+	return nil
+}
+
+// UnmarshalTOML 需要解码器开启 EnableUnmarshalerInterface，
+// 否则 go-toml 无法把同一个字段既解析为字符串又解析为数组
+func (m *MethodList) UnmarshalTOML(node *unstable.Node) error {
+	switch node.Kind {
+	case unstable.String:
+		*m = splitMethods(string(node.Data))
+		return nil
+	case unstable.Array:
+		var list MethodList
+		for it := node.Children(); it.Next(); {
+			child := it.Node()
+			if child.Kind != unstable.String {
+				return fmt.Errorf("method list must contain only strings")
+			}
+			list = append(list, string(child.Data))
+		}
+		*m = list
+		return nil
+	default:
+		return fmt.Errorf("method must be a string or a list of strings")
+	}
+}
+
+// expandMethods 将声明了多个方法的端点展开为每个方法一个端点，展开后的端点共享其余配置
+func expandMethods(config *Config) error {
+	for i := range config.Services {
+		service := &config.Services[i]
+		var endpoints []Endpoint
+		for _, endpoint := range service.Endpoints {
+			if len(endpoint.Methods) == 0 {
+				endpoints = append(endpoints, endpoint)
+				continue
+			}
+			for _, method := range endpoint.Methods {
+				method = strings.ToUpper(strings.TrimSpace(method))
+				if method == "" {
+					return fmt.Errorf("endpoint %s in service %s has an empty method", service.BasePath+endpoint.Path, service.Name)
+				}
+				e := endpoint
+				e.Method = method
+				e.Methods = nil
+				endpoints = append(endpoints, e)
+			}
+		}
+		service.Endpoints = endpoints
+	}
+	return nil
+}
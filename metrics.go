@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsPath = "/__metrics"
+	// routeTemplateKey 保存没有 gin 路由模板的请求（如正则端点）所用的路由标签
+	routeTemplateKey = "mock.route"
+	unmatchedRoute   = "<unmatched>"
+)
+
+// metrics 保存 Prometheus 指标，使用独立的 registry，重载配置时保持不变
+type metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	// fileErrors 按文件统计响应文件读取失败，文件来自配置，标签基数有限
+	fileErrors *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mock_http_requests_total",
+			Help: "Number of HTTP requests served by the mock server.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mock_http_request_duration_seconds",
+			Help:    "Latency of HTTP requests served by the mock server.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		fileErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mock_response_file_errors_total",
+			Help: "Number of requests whose response file could not be read.",
+		}, []string{"file"}),
+	}
+	m.registry.MustRegister(m.requests, m.latency, m.fileErrors)
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// metricsMiddleware 按路由模板而不是原始路径统计，避免标签基数随路径参数膨胀
+func metricsMiddleware(m *metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.GetString(routeTemplateKey)
+		}
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		m.requests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		m.latency.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCert 生成证书，parent 为 nil 时生成自签名的 CA
+func newTestCert(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, any(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestMutualTLS(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /secure
+        method: GET
+        responseBody: ok
+`}, routerOptions{})
+	ca := newTestCert(t, "test ca", nil)
+	caFile := filepath.Join(s.dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	serverTLS, err := TLSConfig{ClientCAFile: caFile, RequireClientCert: true}.serverTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(s.handler)
+	srv.TLS = serverTLS
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(cert *tls.Certificate) (*http.Response, error) {
+		transport := srv.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		}
+		defer transport.CloseIdleConnections()
+		return (&http.Client{Transport: transport}).Get(srv.URL + "/secure")
+	}
+
+	trusted := newTestCert(t, "trusted client", &ca)
+	resp, err := get(&trusted)
+	if err != nil {
+		t.Fatalf("trusted client: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("trusted client status = %d", resp.StatusCode)
+	}
+
+	// 没有证书或证书不是由配置的 CA 签发时，握手失败，请求不会到达 handler
+	other := newTestCert(t, "other ca", nil)
+	untrusted := newTestCert(t, "untrusted client", &other)
+	for name, cert := range map[string]*tls.Certificate{"no cert": nil, "untrusted cert": &untrusted} {
+		if resp, err := get(cert); err == nil {
+			resp.Body.Close()
+			t.Fatalf("%s: got status %d, want handshake failure", name, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+const endpointTypeMultipart = "multipart"
+
+// MultipartPart 是 multipart 响应中的一部分。Name 不为空时写出 Content-Disposition，
+// 用于 multipart/form-data 响应
+type MultipartPart struct {
+	Name         string            `yaml:"name" json:"name" toml:"name"`
+	Filename     string            `yaml:"filename" json:"filename" toml:"filename"`
+	ContentType  string            `yaml:"contentType" json:"contentType" toml:"contentType"`
+	Headers      map[string]string `yaml:"headers" json:"headers" toml:"headers"`
+	ResponseFile string            `yaml:"responseFile" json:"responseFile" toml:"responseFile"`
+	ResponseBody string            `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+}
+
+func validateMultipart(endpoint Endpoint) error {
+	if len(endpoint.Parts) == 0 {
+		return fmt.Errorf("multipart endpoint has no parts")
+	}
+	for i, part := range endpoint.Parts {
+		if part.ResponseFile == "" && part.ResponseBody == "" {
+			return fmt.Errorf("part %d has neither responseFile nor responseBody", i)
+		}
+	}
+	return nil
+}
+
+func (p MultipartPart) header() textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	for k, v := range p.Headers {
+		h.Set(k, v)
+	}
+	if p.Name != "" {
+		disposition := fmt.Sprintf(`form-data; name="%s"`, p.Name)
+		if p.Filename != "" {
+			disposition += fmt.Sprintf(`; filename="%s"`, p.Filename)
+		}
+		h.Set("Content-Disposition", disposition)
+	}
+	contentType := p.ContentType
+	if contentType == "" {
+		contentType = contentTypeFor(p.ResponseFile)
+	}
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+// newMultipartHandler 按顺序输出各部分，文件部分直接从磁盘复制，不整体读入内存
+func newMultipartHandler(hc handlerContext, endpoint Endpoint) gin.HandlerFunc {
+	subtype := endpoint.MultipartType
+	if subtype == "" {
+		subtype = "form-data"
+	}
+	statusCode := endpoint.StatusCode
+	if statusCode == 0 {
+		statusCode = 200
+	}
+	return func(c *gin.Context) {
+		// 先确认所有文件都能打开，避免响应头写出后才发现错误
+		files := make([]*os.File, len(endpoint.Parts))
+		defer func() {
+			for _, f := range files {
+				if f != nil {
+					f.Close()
+				}
+			}
+		}()
+		for i, part := range endpoint.Parts {
+			if part.ResponseFile == "" {
+				continue
+			}
+			f, _, err := openResponseFile(part.ResponseFile)
+			if err != nil {
+				hc.fileError(c, part.ResponseFile, err)
+				return
+			}
+			files[i] = f
+		}
+
+		mw := multipart.NewWriter(c.Writer)
+		for k, v := range endpoint.Headers {
+			c.Header(k, v)
+		}
+		c.Header("Content-Type", fmt.Sprintf("multipart/%s; boundary=%s", subtype, mw.Boundary()))
+		c.Status(statusCode)
+
+		for i, part := range endpoint.Parts {
+			w, err := mw.CreatePart(part.header())
+			if err != nil {
+				return
+			}
+			if files[i] != nil {
+				_, err = io.Copy(w, files[i])
+			} else {
+				_, err = io.WriteString(w, part.ResponseBody)
+			}
+			if err != nil {
+				return
+			}
+		}
+		mw.Close()
+	}
+}
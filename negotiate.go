@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ResponseVariant 是 variants 列表中的一项，根据请求的 Accept 头选择
+type ResponseVariant struct {
+	MediaType    string `yaml:"mediaType" json:"mediaType" toml:"mediaType"`
+	StatusCode   int    `yaml:"statusCode" json:"statusCode" toml:"statusCode"`
+	ResponseFile string `yaml:"responseFile" json:"responseFile" toml:"responseFile"`
+	ResponseBody string `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+}
+
+// acceptRange 是 Accept 头中的一个媒体范围，例如 text/* 或 application/json;q=0.8
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		r := acceptRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// quality 返回媒体类型在 Accept 中的权重，取最具体的那个匹配范围
+func quality(ranges []acceptRange, mediaType string) float64 {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	typ, subtype, _ := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// negotiateVariant 返回最符合 Accept 头的 variant 下标，权重相同时取靠前的一项，
+// 没有 Accept 头或没有可接受的 variant 时使用第一项
+func negotiateVariant(accept string, variants []ResponseVariant) int {
+	if accept == "" {
+		return 0
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := 0, 0.0
+	for i, v := range variants {
+		if q := quality(ranges, v.MediaType); q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+var openAPIPathParam = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// openAPISpec 是 OpenAPI 3 文档的通用表示，只解析生成 mock 需要的部分
+type openAPISpec struct {
+	root map[string]interface{}
+}
+
+// loadOpenAPI 解析 OpenAPI 3 文档（YAML 或 JSON），为每个 operation 生成一个端点，
+// 响应体取自 example/examples，没有示例时根据 schema 生成
+func loadOpenAPI(filename string) (*Service, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %v", err)
+	}
+	root, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid openapi spec: top level is not an object")
+	}
+	if version, _ := root["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q, expected 3.x", version)
+	}
+	spec := &openAPISpec{root: root}
+
+	service := &Service{Name: "openapi", BasePath: spec.basePath(), source: filename}
+	if info, ok := root["info"].(map[string]interface{}); ok {
+		if title, ok := info["title"].(string); ok && title != "" {
+			service.Name = title
+		}
+	}
+
+	paths, _ := root["paths"].(map[string]interface{})
+	pathNames := make([]string, 0, len(paths))
+	for p := range paths {
+		pathNames = append(pathNames, p)
+	}
+	sort.Strings(pathNames)
+
+	for _, p := range pathNames {
+		item, _ := spec.resolve(paths[p]).(map[string]interface{})
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			endpoint, err := spec.endpoint(p, method, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %v", strings.ToUpper(method), p, err)
+			}
+			service.Endpoints = append(service.Endpoints, endpoint)
+		}
+	}
+	log.Printf("Loaded %d endpoints from openapi spec %s", len(service.Endpoints), filename)
+	return service, nil
+}
+
+func (s *openAPISpec) basePath() string {
+	servers, _ := s.root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]interface{})
+	rawURL, _ := server["url"].(string)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// resolve 解析文档内部的 $ref 引用，例如 #/components/schemas/User
+func (s *openAPISpec) resolve(v interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return v
+		}
+		var current interface{} = s.root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			current = obj[part]
+		}
+		v = current
+	}
+	return v
+}
+
+func (s *openAPISpec) endpoint(path, method string, op map[string]interface{}) (Endpoint, error) {
+	endpoint := Endpoint{
+		Path:   openAPIPathParam.ReplaceAllString(path, ":$1"),
+		Method: strings.ToUpper(method),
+	}
+
+	responses, _ := op["responses"].(map[string]interface{})
+	code, rawResponse := pickOpenAPIResponse(responses)
+	endpoint.StatusCode = code
+	response, _ := s.resolve(rawResponse).(map[string]interface{})
+
+	content, _ := response["content"].(map[string]interface{})
+	mediaType, media := pickMediaType(content)
+	if media == nil {
+		// 没有响应体的 operation（例如 204）返回空 body
+		endpoint.allowEmpty = true
+		return endpoint, nil
+	}
+
+	var body interface{}
+	if example, ok := media["example"]; ok {
+		body = example
+	} else if examples, ok := media["examples"].(map[string]interface{}); ok && len(examples) > 0 {
+		names := make([]string, 0, len(examples))
+		for name := range examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		example, _ := s.resolve(examples[names[0]]).(map[string]interface{})
+		body = example["value"]
+	} else {
+		body = s.sample(media["schema"], 0)
+	}
+
+	if str, ok := body.(string); ok && !strings.Contains(mediaType, "json") {
+		endpoint.ResponseBody = str
+	} else {
+		encoded, err := json.MarshalIndent(body, "", "  ")
+		if err != nil {
+			return endpoint, err
+		}
+		endpoint.ResponseBody = string(encoded)
+	}
+	endpoint.Headers = map[string]string{"Content-Type": mediaType}
+	return endpoint, nil
+}
+
+// pickOpenAPIResponse 优先选择最小的 2xx 状态码，其次是 default，最后是任意一个
+func pickOpenAPIResponse(responses map[string]interface{}) (int, interface{}) {
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			if n, err := strconv.Atoi(code); err == nil {
+				return n, responses[code]
+			}
+		}
+	}
+	if response, ok := responses["default"]; ok {
+		return 200, response
+	}
+	for _, code := range codes {
+		if n, err := strconv.Atoi(code); err == nil {
+			return n, responses[code]
+		}
+	}
+	return 200, nil
+}
+
+func pickMediaType(content map[string]interface{}) (string, map[string]interface{}) {
+	if media, ok := content["application/json"].(map[string]interface{}); ok {
+		return "application/json", media
+	}
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		if media, ok := content[t].(map[string]interface{}); ok {
+			return t, media
+		}
+	}
+	return "", nil
+}
+
+// sample 根据 schema 生成示例数据，优先使用 schema 中的 example/default
+func (s *openAPISpec) sample(schemaValue interface{}, depth int) interface{} {
+	schema, ok := s.resolve(schemaValue).(map[string]interface{})
+	if !ok || depth > 8 {
+		return nil
+	}
+	if example, ok := schema["example"]; ok {
+		return example
+	}
+	if def, ok := schema["default"]; ok {
+		return def
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		if list, ok := schema[key].([]interface{}); ok && len(list) > 0 {
+			if key != "allOf" {
+				return s.sample(list[0], depth+1)
+			}
+			merged := map[string]interface{}{}
+			for _, item := range list {
+				if obj, ok := s.sample(item, depth+1).(map[string]interface{}); ok {
+					for k, v := range obj {
+						merged[k] = v
+					}
+				}
+			}
+			return merged
+		}
+	}
+
+	switch schema["type"] {
+	case "object", nil:
+		props, _ := schema["properties"].(map[string]interface{})
+		obj := make(map[string]interface{}, len(props))
+		for name, prop := range props {
+			obj[name] = s.sample(prop, depth+1)
+		}
+		return obj
+	case "array":
+		return []interface{}{s.sample(schema["items"], depth+1)}
+	case "string":
+		return "string"
+	case "integer":
+		return 0
+	case "number":
+		return 0.0
+	case "boolean":
+		return false
+	}
+	return nil
+}
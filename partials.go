@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// loadPartials 解析 templateDir 中的所有文件，每个文件按去掉扩展名的文件名注册为一个模板，
+// 文件中用 define 定义的模板同样可用。返回的模板集合在渲染时克隆，互不影响
+func loadPartials(dir string) (*template.Template, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("templateDir: %v", err)
+	}
+	root := template.New("").Funcs(templateFuncs)
+	var files []string
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		file := filepath.Join(dir, entry.Name())
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if other, ok := seen[name]; ok {
+			return nil, nil, fmt.Errorf("templateDir: %s and %s both define template %q", other, file, name)
+		}
+		seen[name] = file
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("templateDir: %v", err)
+		}
+		if _, err := root.New(name).Parse(string(data)); err != nil {
+			return nil, nil, fmt.Errorf("templateDir: %s: %v", file, err)
+		}
+		files = append(files, file)
+	}
+	return root, files, nil
+}
+
+// newTemplateSet 返回用于解析响应模板的模板集合，配置了 templateDir 时包含其中的 partial
+func newTemplateSet(partials *template.Template) (*template.Template, error) {
+	if partials == nil {
+		return template.New("").Funcs(templateFuncs), nil
+	}
+	return partials.Clone()
+}
+
+// partialFile 返回定义了顶层模板 name 的 partial 文件
+func partialFile(files []string, name string) string {
+	for _, file := range files {
+		base := filepath.Base(file)
+		if strings.TrimSuffix(base, filepath.Ext(base)) == name {
+			return file
+		}
+	}
+	return name
+}
+
+// undefinedTemplates 返回模板树中引用但模板集合里不存在的模板名
+func undefinedTemplates(tree *parse.Tree, set *template.Template) []string {
+	var missing []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			if set.Lookup(n.Name) == nil {
+				missing = append(missing, n.Name)
+			}
+		}
+	}
+	if tree != nil {
+		walk(tree.Root)
+	}
+	return missing
+}
+
+// requestDataFields 是模板上下文中来自请求头和请求体的字段，缓存 key 不包含它们
+var requestDataFields = map[string]bool{"Headers": true, "Body": true}
+
+// readsRequestData 判断模板（包括它调用的其他模板）是否读取 .Headers 或 .Body
+func readsRequestData(tree *parse.Tree, set *template.Template) bool {
+	seen := make(map[string]bool)
+	found := false
+	var walk func(node parse.Node)
+	walkPipe := func(pipe *parse.PipeNode) {
+		if pipe != nil {
+			walk(pipe)
+		}
+	}
+	walk = func(node parse.Node) {
+		if found {
+			return
+		}
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walkPipe(n.Pipe)
+		case *parse.PipeNode:
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			found = requestDataFields[n.Ident[0]]
+		case *parse.VariableNode:
+			// $.Body 这样的引用
+			found = len(n.Ident) > 1 && requestDataFields[n.Ident[1]]
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IfNode:
+			walkPipe(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walkPipe(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walkPipe(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walkPipe(n.Pipe)
+			if t := set.Lookup(n.Name); t != nil && t.Tree != nil && !seen[n.Name] {
+				seen[n.Name] = true
+				walk(t.Tree.Root)
+			}
+		}
+	}
+	if tree != nil {
+		walk(tree.Root)
+	}
+	return found
+}
+
+// validateTemplates 在加载时解析模板端点的响应文件，并检查它们和 partial 引用的模板都存在，
+// 避免缺失的 partial 直到请求时才以 500 暴露出来
+func validateTemplates(config *Config) error {
+	if config.partials != nil {
+		templates := config.partials.Templates()
+		sort.Slice(templates, func(i, j int) bool { return templates[i].Name() < templates[j].Name() })
+		for _, t := range templates {
+			if t.Tree == nil {
+				continue
+			}
+			if missing := undefinedTemplates(t.Tree, config.partials); len(missing) > 0 {
+				return fmt.Errorf("templateDir: %s: template %q references undefined template %q", partialFile(config.partialFiles, t.Tree.ParseName), t.Name(), missing[0])
+			}
+		}
+	}
+	for _, service := range config.Services {
+		for _, endpoint := range service.Endpoints {
+			if !endpoint.Template || endpoint.Type != "" {
+				continue
+			}
+			where := fmt.Sprintf("endpoint %s %s in service %s", endpoint.Method, service.BasePath+endpoint.Path, service.Name)
+			files := append([]string(nil), endpoint.globFiles...)
+			endpoint.mapFiles(func(file string) string {
+				files = append(files, file)
+				return file
+			})
+			for _, file := range files {
+				data, err := readJSONFile(file)
+				if err != nil {
+					// 缺失的文件已由 validateResponseFiles 报告或交给 fallback 处理
+					continue
+				}
+				set, err := newTemplateSet(config.partials)
+				if err != nil {
+					return err
+				}
+				tmpl, err := set.New(file).Parse(string(data))
+				if err != nil {
+					return fmt.Errorf("%s: template %s: %v", where, file, err)
+				}
+				for _, t := range tmpl.Templates() {
+					if missing := undefinedTemplates(t.Tree, tmpl); len(missing) > 0 {
+						if config.Server.TemplateDir == "" {
+							return fmt.Errorf("%s: response file %s references undefined template %q (no server.templateDir configured)", where, file, missing[0])
+						}
+						return fmt.Errorf("%s: response file %s references undefined template %q, not found in templateDir %s", where, file, missing[0], config.Server.TemplateDir)
+					}
+				}
+				if endpoint.CacheTTL > 0 && readsRequestData(tmpl.Tree, tmpl) {
+					return fmt.Errorf("%s sets cacheTTL but template %s reads .Headers or .Body, which the cache key (method and request URI) does not distinguish", where, file)
+				}
+			}
+			if endpoint.CacheTTL > 0 && endpoint.ResponseBody != "" {
+				set, err := newTemplateSet(config.partials)
+				if err != nil {
+					return err
+				}
+				// 解析错误在请求时以 500 报告，这里只检查能解析的模板
+				if tmpl, err := set.New("responseBody").Parse(endpoint.ResponseBody); err == nil && readsRequestData(tmpl.Tree, tmpl) {
+					return fmt.Errorf("%s sets cacheTTL but its responseBody template reads .Headers or .Body, which the cache key (method and request URI) does not distinguish", where)
+				}
+			}
+		}
+	}
+	return nil
+}
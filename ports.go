@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// portRouter 按请求到达的监听端口把请求分派给对应服务的路由，
+// 到达默认监听端口的请求由 fallback 处理
+type portRouter struct {
+	handlers map[int]http.Handler
+	fallback http.Handler
+}
+
+func (p *portRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if addr, ok := req.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr); ok {
+		if h, ok := p.handlers[addr.Port]; ok {
+			h.ServeHTTP(w, req)
+			return
+		}
+	}
+	p.fallback.ServeHTTP(w, req)
+}
+
+// servicePorts 按升序返回服务配置的独立端口
+func servicePorts(config *Config) []int {
+	var ports []int
+	for _, service := range config.Services {
+		if service.Port != 0 && !slices.Contains(ports, service.Port) {
+			ports = append(ports, service.Port)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// servicesOnPort 返回在指定端口上提供服务的服务，port 为 0 表示默认监听端口
+func servicesOnPort(config *Config, port int) []Service {
+	var services []Service
+	for _, service := range config.Services {
+		if service.Port == port {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+func serviceNames(services []Service) string {
+	names := make([]string, len(services))
+	for i, service := range services {
+		names[i] = service.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkPorts 在启动时检查服务端口不与默认监听端口冲突，并返回每个端口的监听地址，
+// 监听地址使用与默认地址相同的主机部分
+func checkPorts(config *Config, defaultAddr string) (map[int]string, error) {
+	host, defaultPort, err := net.SplitHostPort(defaultAddr)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make(map[int]string)
+	for _, port := range servicePorts(config) {
+		if strconv.Itoa(port) == defaultPort {
+			return nil, fmt.Errorf("port %d of services %s is already used by the default listener %s", port, serviceNames(servicesOnPort(config, port)), defaultAddr)
+		}
+		addrs[port] = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	return addrs, nil
+}
+
+// checkPortsUnchanged 拒绝改变服务端口的重载。监听器只在启动时创建，新增端口上的服务无法访问
+func checkPortsUnchanged(ports []int, config *Config) error {
+	if newPorts := servicePorts(config); !slices.Equal(ports, newPorts) {
+		return fmt.Errorf("service ports changed from %v to %v, restart the server to change listeners", ports, newPorts)
+	}
+	return nil
+}
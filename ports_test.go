@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const twoPortConfig = `
+services:
+  - name: billing
+    port: 9001
+    basePath: /
+    endpoints:
+      - path: /s
+        method: GET
+        sequence:
+          - responseBody: billing1
+          - responseBody: billing2
+      - path: /limited
+        method: GET
+        responseBody: billing
+        callLimit: 1
+  - name: users
+    port: 9002
+    basePath: /
+    endpoints:
+      - path: /s
+        method: GET
+        sequence:
+          - responseBody: users1
+          - responseBody: users2
+      - path: /limited
+        method: GET
+        responseBody: users
+        callLimit: 1
+  - name: default
+    basePath: /
+    endpoints:
+      - path: /s
+        method: GET
+        responseBody: default
+`
+
+// getOnPort 模拟请求到达 port 对应的监听器
+func (s *testServer) getOnPort(port int, target string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}))
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestServicePortsRouteByListener(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": twoPortConfig}, routerOptions{})
+	expectBody(t, s.getOnPort(9001, "/s"), "billing1")
+	expectBody(t, s.getOnPort(9002, "/s"), "users1")
+	expectBody(t, s.getOnPort(8080, "/s"), "default")
+	expectBody(t, s.get("/s"), "default")
+}
+
+// 同一路由在不同端口上是不同的端点，序列和 callLimit 计数不能共享
+func TestServicePortsKeepSeparateState(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": twoPortConfig}, routerOptions{})
+	expectBody(t, s.getOnPort(9001, "/s"), "billing1")
+	expectBody(t, s.getOnPort(9002, "/s"), "users1")
+	expectBody(t, s.getOnPort(9001, "/s"), "billing2")
+
+	expectBody(t, s.getOnPort(9001, "/limited"), "billing")
+	expectBody(t, s.getOnPort(9002, "/limited"), "users")
+	expectStatus(t, s.getOnPort(9001, "/limited"), http.StatusTooManyRequests)
+}
+
+func TestCheckPorts(t *testing.T) {
+	config := &Config{Services: []Service{{Name: "a", Port: 9001}, {Name: "b", Port: 8080}}}
+	if _, err := checkPorts(config, ":8080"); err == nil {
+		t.Fatal("expected a collision with the default listener")
+	}
+	config.Services[1].Port = 9002
+	addrs, err := checkPorts(config, "127.0.0.1:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs[9001] != "127.0.0.1:9001" || addrs[9002] != "127.0.0.1:9002" {
+		t.Fatalf("addrs = %v", addrs)
+	}
+	if err := checkPortsUnchanged([]int{9001, 9002}, &Config{Services: []Service{{Port: 9003}}}); err == nil {
+		t.Fatal("expected reload with changed ports to be rejected")
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// postmanCollection 是 Postman v2.1 集合中生成 mock 需要的部分
+type postmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item []postmanItem `json:"item"`
+}
+
+// postmanItem 既可以是请求，也可以是包含子项的文件夹
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  *postmanRequest   `json:"request"`
+	Response []postmanResponse `json:"response"`
+}
+
+type postmanRequest struct {
+	Method string     `json:"method"`
+	URL    postmanURL `json:"url"`
+}
+
+// postmanURL 在集合中可能是字符串，也可能是拆分好的对象
+type postmanURL struct {
+	Raw  string   `json:"raw"`
+	Host []string `json:"host"`
+	Path []string `json:"path"`
+}
+
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		u.Raw = raw
+		return nil
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+type postmanResponse struct {
+	Code   int             `json:"code"`
+	Body   string          `json:"body"`
+	Header []postmanHeader `json:"header"`
+}
+
+type postmanHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+var postmanVariable = regexp.MustCompile(`^\{\{(.+)\}\}$`)
+
+// baseAndPath 返回请求的基础地址（用于区分服务）和 gin 风格的路径，{{var}} 路径段转换为 :var
+func (u postmanURL) baseAndPath() (string, string) {
+	base := strings.Join(u.Host, ".")
+	segments := u.Path
+	if len(u.Host) == 0 && len(u.Path) == 0 && u.Raw != "" {
+		raw := u.Raw
+		if i := strings.IndexAny(raw, "?#"); i >= 0 {
+			raw = raw[:i]
+		}
+		if parsed, err := url.Parse(raw); err == nil && parsed.Host != "" {
+			base = parsed.Scheme + "://" + parsed.Host
+			raw = parsed.Path
+		} else if i := strings.Index(raw, "/"); i >= 0 {
+			base, raw = raw[:i], raw[i:]
+		} else {
+			base, raw = raw, ""
+		}
+		segments = strings.Split(strings.Trim(raw, "/"), "/")
+	}
+
+	var parts []string
+	for _, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		if m := postmanVariable.FindStringSubmatch(seg); m != nil {
+			seg = ":" + m[1]
+		}
+		parts = append(parts, seg)
+	}
+	return base, "/" + strings.Join(parts, "/")
+}
+
+// loadPostman 解析 Postman v2.1 集合，每个基础地址生成一个服务，保存的第一个示例响应作为响应体
+func loadPostman(filename string) ([]Service, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("invalid postman collection: %v", err)
+	}
+
+	name := collection.Info.Name
+	if name == "" {
+		name = "postman"
+	}
+	var services []Service
+	serviceIndex := make(map[string]int)
+	seen := make(map[string]bool)
+
+	var walk func(items []postmanItem)
+	walk = func(items []postmanItem) {
+		for _, item := range items {
+			if item.Request == nil {
+				walk(item.Item)
+				continue
+			}
+			method := strings.ToUpper(item.Request.Method)
+			if method == "" {
+				method = "GET"
+			}
+			base, path := item.Request.URL.baseAndPath()
+			key := method + " " + path
+			if seen[key] {
+				log.Printf("Warning: postman request %q duplicates %s, skipped", item.Name, key)
+				continue
+			}
+			seen[key] = true
+
+			endpoint := Endpoint{Path: path, Method: method}
+			if len(item.Response) == 0 {
+				log.Printf("Warning: postman request %q (%s) has no saved example, serving an empty 200 body", item.Name, key)
+				endpoint.allowEmpty = true
+			} else {
+				example := item.Response[0]
+				endpoint.StatusCode = example.Code
+				endpoint.ResponseBody = example.Body
+				endpoint.allowEmpty = example.Body == ""
+				for _, h := range example.Header {
+					if strings.EqualFold(h.Key, "Content-Type") {
+						endpoint.Headers = map[string]string{"Content-Type": h.Value}
+					}
+				}
+			}
+
+			i, ok := serviceIndex[base]
+			if !ok {
+				i = len(services)
+				serviceIndex[base] = i
+				serviceName := name
+				if base != "" {
+					serviceName = name + " (" + base + ")"
+				}
+				services = append(services, Service{Name: serviceName, source: filename})
+			}
+			services[i].Endpoints = append(services[i].Endpoints, endpoint)
+		}
+	}
+	walk(collection.Item)
+
+	log.Printf("Loaded %d endpoints from postman collection %s", len(seen), filename)
+	return services, nil
+}
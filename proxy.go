@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+func parseUpstream(upstream string) (*url.URL, error) {
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream %q: %v", upstream, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid upstream %q: scheme and host are required", upstream)
+	}
+	return target, nil
+}
+
+// newProxyHandler 将未匹配任何 mock 端点的请求转发到真实的上游服务，rec 不为空时录制上游响应
+func newProxyHandler(target *url.URL, rec *recorder) gin.HandlerFunc {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	if rec != nil {
+		director := proxy.Director
+		proxy.Director = func(req *http.Request) {
+			director(req)
+			// 录制时要求上游返回未压缩的内容，保证响应文件可直接回放
+			req.Header.Del("Accept-Encoding")
+		}
+		proxy.ModifyResponse = rec.record
+	}
+	return func(c *gin.Context) {
+		log.Printf("Proxying %s %s to upstream %s", c.Request.Method, c.Request.URL.RequestURI(), target.Host)
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}
+}
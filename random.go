@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand 是并发安全的随机数源，所有随机行为（加权响应等）共用，
+// 可通过 -seed 固定种子以便测试复现
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+var rng = &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func (l *lockedRand) seed(seed int64) {
+	l.mu.Lock()
+	l.r = rand.New(rand.NewSource(seed))
+	l.mu.Unlock()
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Int63n(n)
+}
+
+// weightedPicker 按权重从多个响应中随机选择，权重为 0 时视为 1
+type weightedPicker struct {
+	responses []mockResponse
+	weights   []int64
+	total     int64
+}
+
+func newWeightedPicker(responses []WeightedResponse, defaultStatus int) *weightedPicker {
+	if len(responses) == 0 {
+		return nil
+	}
+	p := &weightedPicker{}
+	for i, r := range responses {
+		statusCode := r.StatusCode
+		if statusCode == 0 {
+			statusCode = defaultStatus
+		}
+		weight := int64(r.Weight)
+		if weight == 0 {
+			weight = 1
+		}
+		p.responses = append(p.responses, mockResponse{
+			file:       r.ResponseFile,
+			body:       []byte(r.ResponseBody),
+			statusCode: statusCode,
+			rule:       fmt.Sprintf("responses[%d]", i),
+		})
+		p.weights = append(p.weights, weight)
+		p.total += weight
+	}
+	return p
+}
+
+func (p *weightedPicker) pick() mockResponse {
+	if len(p.responses) == 1 {
+		return p.responses[0]
+	}
+	n := rng.Int63n(p.total)
+	for i, w := range p.weights {
+		if n < w {
+			return p.responses[i]
+		}
+		n -= w
+	}
+	return p.responses[len(p.responses)-1]
+}
+
+// latency 描述端点的响应延迟分布：在 [base-jitter, base+jitter] 内均匀取值，
+// 并以 spikeRate 的概率改用 spike 延迟，模拟偶发的长尾请求
+type latency struct {
+	base, jitter, spike time.Duration
+	spikeRate           float64
+}
+
+func (l latency) sample() time.Duration {
+	if l.spikeRate > 0 && rng.Float64() < l.spikeRate {
+		return l.spike
+	}
+	d := l.base
+	if l.jitter > 0 {
+		d += time.Duration(rng.Int63n(int64(2*l.jitter)+1)) - l.jitter
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitConfig 配置令牌桶限流：每秒补充 rps 个令牌，最多累积 burst 个
+type RateLimitConfig struct {
+	RPS   float64 `yaml:"rps" json:"rps" toml:"rps"`
+	Burst int     `yaml:"burst" json:"burst" toml:"burst"`
+}
+
+func (r *RateLimitConfig) validate() error {
+	if r.RPS <= 0 {
+		return fmt.Errorf("rateLimit.rps must be positive")
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("rateLimit.burst must not be negative")
+	}
+	return nil
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(cfg *RateLimitConfig) *tokenBucket {
+	burst := float64(cfg.Burst)
+	if burst == 0 {
+		burst = math.Max(1, math.Ceil(cfg.RPS))
+	}
+	return &tokenBucket{rate: cfg.RPS, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// take 尝试取出一个令牌，失败时返回需要等待的时间
+func (b *tokenBucket) take() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+// rateLimitMiddleware 超出限流时返回 429 和 Retry-After（秒，向上取整）
+func rateLimitMiddleware(bucket *tokenBucket) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, wait := bucket.take()
+		if ok {
+			return
+		}
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+	}
+}
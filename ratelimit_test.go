@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fire 并发发送 n 个请求，返回 429 的数量；其余请求必须成功
+func fire(t *testing.T, s *testServer, target string, n int) int {
+	t.Helper()
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			s.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			codes[i] = w.Code
+			if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				codes[i] = -1
+			}
+		}(i)
+	}
+	wg.Wait()
+	limited := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+		case http.StatusTooManyRequests:
+			limited++
+		default:
+			t.Fatalf("unexpected status %d (429 without Retry-After is reported as -1)", code)
+		}
+	}
+	return limited
+}
+
+func TestEndpointRateLimit(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /a
+        method: GET
+        responseBody: a
+        rateLimit:
+          rps: 0.01
+          burst: 3
+      - path: /b
+        method: GET
+        responseBody: b
+        rateLimit:
+          rps: 0.01
+          burst: 2
+`}, routerOptions{})
+	if n := fire(t, s, "/a", 10); n != 7 {
+		t.Fatalf("/a got %d 429s, want 7", n)
+	}
+	// 每个端点有自己的令牌桶，/a 用完不影响 /b
+	if n := fire(t, s, "/b", 10); n != 8 {
+		t.Fatalf("/b got %d 429s, want 8", n)
+	}
+}
+
+func TestGlobalRateLimit(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": `
+server:
+  rateLimit:
+    rps: 0.01
+    burst: 4
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /a
+        method: GET
+        responseBody: a
+      - path: /b
+        method: GET
+        responseBody: b
+`}, routerOptions{})
+	limited := fire(t, s, "/a", 3) + fire(t, s, "/b", 3)
+	if limited != 2 {
+		t.Fatalf("got %d 429s across endpoints, want 2", limited)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const recordedConfigName = "recorded.yaml"
+
+// recorder 将代理得到的上游响应保存为响应文件，并把对应的端点配置追加到 recorded.yaml，
+// 之后可以直接用 -config 指向该目录离线回放
+type recorder struct {
+	dir       string
+	overwrite bool
+
+	mu       sync.Mutex
+	recorded map[string]bool
+}
+
+func newRecorder(dir string, overwrite bool) (*recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &recorder{dir: dir, overwrite: overwrite, recorded: make(map[string]bool)}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// recordFileName 将 method+path 转换为安全的文件名，例如 GET /api/users/1 -> GET_api_users_1.json
+func recordFileName(method, path, contentType string) string {
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(path, "_"), "_")
+	if name == "" {
+		name = "root"
+	}
+	ext := ".json"
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "application/json" {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return strings.ToUpper(method) + "_" + name + ext
+}
+
+// record 在 ReverseProxy.ModifyResponse 中调用，读取响应体后重新放回，不影响返回给客户端的内容
+func (r *recorder) record(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	req := resp.Request
+	key := req.Method + " " + req.URL.Path
+	file := filepath.Join(r.dir, recordFileName(req.Method, req.URL.Path, resp.Header.Get("Content-Type")))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, statErr := os.Stat(file)
+	exists := statErr == nil
+	if exists && !r.overwrite {
+		log.Printf("Skipping record of %s: %s already exists", key, file)
+		return nil
+	}
+	if err := os.WriteFile(file, body, 0o644); err != nil {
+		log.Printf("Failed to record %s: %v", key, err)
+		return nil
+	}
+	log.Printf("Recorded %s to %s", key, file)
+
+	// 已存在的文件说明配置片段之前已经追加过，避免产生重复路由
+	if exists || r.recorded[key] {
+		return nil
+	}
+	r.recorded[key] = true
+	// recorded.yaml 与响应文件在同一目录，相对路径按配置文件所在目录解析
+	if err := r.appendEndpoint(req.Method, req.URL.Path, filepath.Base(file), resp.StatusCode); err != nil {
+		log.Printf("Failed to append recorded endpoint %s: %v", key, err)
+	}
+	return nil
+}
+
+func (r *recorder) appendEndpoint(method, path, file string, statusCode int) error {
+	configFile := filepath.Join(r.dir, recordedConfigName)
+	_, err := os.Stat(configFile)
+	isNew := os.IsNotExist(err)
+
+	f, err := os.OpenFile(configFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var buf strings.Builder
+	if isNew {
+		buf.WriteString("services:\n  - name: recorded\n    basePath: \"\"\n    endpoints:\n")
+	}
+	fmt.Fprintf(&buf, "      - path: %q\n        method: %s\n        responseFile: %q\n        statusCode: %d\n", path, method, file, statusCode)
+	_, err = f.WriteString(buf.String())
+	return err
+}
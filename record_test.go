@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 录制得到的目录可以直接作为配置加载回放。-record 通常是相对路径，
+// 写入 recorded.yaml 的响应文件路径必须相对于该目录而不是当前工作目录
+func TestRecordThenReplay(t *testing.T) {
+	base := t.TempDir()
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Chdir(base); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := newRecorder("recorded", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ path, body string }{
+		{"/api/users", `{"users":[]}`},
+		{"/api/users/1", `{"id":1}`},
+	} {
+		resp := &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(tc.body)),
+			Request:    httptest.NewRequest(http.MethodGet, tc.path, nil),
+		}
+		if err := rec.record(resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replay := func(path string) {
+		t.Helper()
+		config, err := loadConfig(path, loadOptions{})
+		if err != nil {
+			t.Fatalf("replay recorded config %s: %v", path, err)
+		}
+		engine, err := setupRouter(config, routerOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+		expectStatus(t, w, 200)
+		expectBody(t, w, `{"id":1}`)
+	}
+	replay("./recorded")
+	// 从其他目录启动时同样能找到响应文件
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	replay(filepath.Join(base, "recorded"))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+)
+
+const endpointTypeRedirect = "redirect"
+
+func isRedirectStatus(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// validateRedirect 在加载时检查 location 和状态码，location 按模板预先解析以尽早发现语法错误
+func validateRedirect(endpoint Endpoint) error {
+	if endpoint.Location == "" {
+		return fmt.Errorf("redirect endpoint has no location")
+	}
+	if endpoint.StatusCode != 0 && !isRedirectStatus(endpoint.StatusCode) {
+		return fmt.Errorf("redirect endpoint has statusCode %d, expected 301, 302, 307 or 308", endpoint.StatusCode)
+	}
+	if _, err := template.New(endpoint.Path).Funcs(templateFuncs).Parse(endpoint.Location); err != nil {
+		return fmt.Errorf("invalid location template: %v", err)
+	}
+	return nil
+}
+
+// newRedirectHandler 按模板渲染 location 后重定向，便于把请求中的参数带回去，例如 OAuth 回调中的 state
+func newRedirectHandler(hc handlerContext, endpoint Endpoint) gin.HandlerFunc {
+	statusCode := endpoint.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusFound
+	}
+	return func(c *gin.Context) {
+		location, err := renderTemplate(endpoint.Path, []byte(endpoint.Location), c, hc.config.partials)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		for k, v := range endpoint.Headers {
+			c.Header(k, v)
+		}
+		setCookies(c, endpoint.Cookies)
+		c.Redirect(statusCode, string(location))
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jsonPathStep 是 JSONPath 中的一段，key 为空时按 index 访问数组
+type jsonPathStep struct {
+	key   string
+	index int
+}
+
+// parseJSONPath 解析 JSONPath 的常用子集：$.a.b、$.items[0]、$['a-b']
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath %q must start with $", path)
+	}
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("JSONPath %q has an empty field name", path)
+			}
+			steps = append(steps, jsonPathStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q has an unclosed [", path)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("JSONPath %q has an invalid index %q", path, inner)
+			}
+			steps = append(steps, jsonPathStep{index: index})
+		default:
+			return nil, fmt.Errorf("JSONPath %q has unexpected %q", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+func evalJSONPath(data interface{}, steps []jsonPathStep) (interface{}, bool) {
+	current := data
+	for _, step := range steps {
+		if step.key != "" {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = obj[step.key]; !ok {
+				return nil, false
+			}
+			continue
+		}
+		arr, ok := current.([]interface{})
+		if !ok || step.index >= len(arr) {
+			return nil, false
+		}
+		current = arr[step.index]
+	}
+	return current, true
+}
+
+// setField 按以点分隔的路径写入字段，中间缺少的对象会被创建
+func setField(obj map[string]interface{}, field string, value interface{}) {
+	keys := strings.Split(field, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			obj[key] = next
+		}
+		obj = next
+	}
+	obj[keys[len(keys)-1]] = value
+}
+
+// reflectFields 将请求体中 JSONPath 取到的值写入响应对象的对应字段。
+// 路径没有匹配时不写入该字段；响应必须是 JSON 对象
+func reflectFields(c *gin.Context, data []byte, mapping map[string]string) ([]byte, error) {
+	var response map[string]interface{}
+	// 按 json.Number 解码，响应中其他字段的大整数 ID 不会因 float64 往返而改变
+	if err := unmarshalJSONNumbers(data, &response); err != nil {
+		return nil, fmt.Errorf("reflect requires a JSON object response: %v", err)
+	}
+	// 请求体同样按 json.Number 解码，反射的大整数保持请求中的原值
+	body := parseJSONBodyNumbers(c)
+	for field, path := range mapping {
+		steps, err := parseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := evalJSONPath(body, steps)
+		if !ok {
+			if gin.IsDebugging() {
+				log.Printf("Debug: reflect path %s did not match the request body, field %s left absent", path, field)
+			}
+			continue
+		}
+		setField(response, field, value)
+	}
+	return json.Marshal(response)
+}
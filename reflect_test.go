@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReflectKeepsLargeIntegers(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"config.yaml": `
+services:
+  - name: svc
+    basePath: /
+    endpoints:
+      - path: /users
+        method: POST
+        responseFile: user.json
+        reflect:
+          name: $.name
+`,
+		"user.json": `{"id": 9007199254740993, "name": ""}`,
+	}, routerOptions{})
+	w := s.do(http.MethodPost, "/users", strings.NewReader(`{"name":"bob"}`), "Content-Type", "application/json")
+	expectStatus(t, w, http.StatusOK)
+	expectBody(t, w, `{"id":9007199254740993,"name":"bob"}`)
+}
+
+// 从请求体反射的大整数和小数同样保持原样
+func TestReflectKeepsLargeIntegersFromRequest(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"config.yaml": `
+services:
+  - name: svc
+    basePath: /
+    endpoints:
+      - path: /orders
+        method: POST
+        responseBody: '{"status": "created"}'
+        reflect:
+          userId: $.user.id
+          amount: $.amount
+`,
+	}, routerOptions{})
+	w := s.do(http.MethodPost, "/orders", strings.NewReader(`{"user":{"id":9007199254740993},"amount":1.10}`), "Content-Type", "application/json")
+	expectStatus(t, w, http.StatusOK)
+	expectBody(t, w, `{"amount":1.10,"status":"created","userId":9007199254740993}`)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// regexRoute 是 pathRegex 端点，path 按正则表达式匹配完整的请求路径
+type regexRoute struct {
+	method   string
+	path     string
+	re       *regexp.Regexp
+	handlers []gin.HandlerFunc
+}
+
+// compilePathRegex 将 basePath 作为字面量前缀，与端点的正则拼接后整体锚定
+func compilePathRegex(basePath, pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^" + regexp.QuoteMeta(basePath) + "(?:" + pattern + ")$")
+}
+
+// params 将捕获组转换为路径参数，命名分组用组名，其余按序号命名（1、2……）
+func (rt regexRoute) params(groups []string) gin.Params {
+	var params gin.Params
+	for i, name := range rt.re.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		params = append(params, gin.Param{Key: name, Value: groups[i]})
+	}
+	return params
+}
+
+// regexDispatcher 在 gin 的路由都未命中时按配置顺序尝试正则端点，
+// 因此精确路径和参数路径总是优先；都不匹配时交给 fallback
+func regexDispatcher(routes []regexRoute, fallback gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, rt := range routes {
+			if !strings.EqualFold(rt.method, c.Request.Method) && !strings.EqualFold(rt.method, methodAny) {
+				continue
+			}
+			groups := rt.re.FindStringSubmatch(c.Request.URL.Path)
+			if groups == nil {
+				continue
+			}
+			c.Params = append(c.Params, rt.params(groups)...)
+			c.Set(routeTemplateKey, rt.path)
+			for _, h := range rt.handlers {
+				h(c)
+				if c.IsAborted() {
+					return
+				}
+			}
+			return
+		}
+		if fallback != nil {
+			fallback(c)
+			return
+		}
+		// 与 gin 默认的 404 响应保持一致
+		c.String(http.StatusNotFound, "404 page not found")
+	}
+}
+
+// regexAllowedMethods 返回路径能匹配上的正则端点所配置的方法，用于 405 响应的 Allow 头
+func regexAllowedMethods(routes []regexRoute, path string) []string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, rt := range routes {
+		if !rt.re.MatchString(path) {
+			continue
+		}
+		candidates := []string{strings.ToUpper(rt.method)}
+		if strings.EqualFold(rt.method, methodAny) {
+			candidates = anyMethods
+		}
+		for _, m := range candidates {
+			if !seen[m] {
+				seen[m] = true
+				methods = append(methods, m)
+			}
+		}
+	}
+	return methods
+}
+
+// regexMethodNotAllowed 在路径只匹配其他方法的正则端点时返回 405，否则交给 next
+func regexMethodNotAllowed(routes []regexRoute, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if methods := regexAllowedMethods(routes, c.Request.URL.Path); len(methods) > 0 {
+			c.Header("Allow", strings.Join(methods, ", "))
+			c.String(http.StatusMethodNotAllowed, "405 method not allowed")
+			return
+		}
+		if next != nil {
+			next(c)
+			return
+		}
+		c.String(http.StatusNotFound, "404 page not found")
+	}
+}
+
+// regexNoMethod 用作 gin 的 NoMethod 处理函数。gin 只检查普通路由，这里先尝试正则端点，
+// 都不匹配时把正则端点的方法补进 gin 生成的 Allow 头，由 gin 写出 405
+func regexNoMethod(routes []regexRoute) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allow := c.Writer.Header().Get("Allow")
+		c.Writer.Header().Del("Allow")
+		regexDispatcher(routes, func(c *gin.Context) {
+			methods := strings.Split(allow, ", ")
+			for _, m := range regexAllowedMethods(routes, c.Request.URL.Path) {
+				if !slices.Contains(methods, m) {
+					methods = append(methods, m)
+				}
+			}
+			c.Header("Allow", strings.Join(methods, ", "))
+		})(c)
+	}
+}
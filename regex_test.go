@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+)
+
+const regexConfig = `
+services:
+  - name: api
+    basePath: /api
+    endpoints:
+      - path: /files/(?P<name>[a-z]+)\.(json|xml)
+        method: GET
+        pathRegex: true
+        template: true
+        responseBody: "{{.Params.name}} as {{index .Params \"2\"}}"
+      - path: /files/readme.json
+        method: GET
+        responseBody: exact
+      - path: /files/:name
+        method: DELETE
+        responseBody: "deleted {{.Params.name}}"
+        template: true
+`
+
+func TestPathRegexCaptureGroups(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": regexConfig}, routerOptions{})
+	expectBody(t, s.get("/api/files/report.json"), "report as json")
+	expectBody(t, s.get("/api/files/notes.xml"), "notes as xml")
+	// 正则整体锚定，basePath 按字面量匹配
+	expectStatus(t, s.get("/api/files/report.json.bak"), 404)
+	expectStatus(t, s.get("/files/report.json"), 404)
+}
+
+func TestPathRegexAfterExactRoutes(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": regexConfig}, routerOptions{})
+	expectBody(t, s.get("/api/files/readme.json"), "exact")
+	expectBody(t, s.do("DELETE", "/api/files/report.json", nil), "deleted report.json")
+}
+
+// 开启 405 时，方法不匹配的请求的 Allow 头同时列出普通路由和正则端点的方法
+func TestPathRegexMethodNotAllowed(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": regexConfig}, routerOptions{MethodNotAllowed: true})
+	w := s.do("PUT", "/api/files/report.json", nil)
+	expectStatus(t, w, 405)
+	if got := w.Header().Get("Allow"); got != "DELETE, GET" {
+		t.Fatalf("Allow = %q, want DELETE, GET", got)
+	}
+}
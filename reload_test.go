@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func reloadConfig(version int) string {
+	return fmt.Sprintf(`
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /v
+        method: GET
+        responseBody: v%d
+        headers:
+          X-Version: "%d"
+`, version, version)
+}
+
+// 配合 go test -race 运行：请求与重载并发进行时，请求只能通过 active 读取配置和路由
+func TestConcurrentReloadAndRequests(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": reloadConfig(0)}, routerOptions{})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var bad atomic.Int64
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				w := httptest.NewRecorder()
+				s.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v", nil))
+				if w.Code != http.StatusOK || w.Body.String() != "v"+w.Header().Get("X-Version") {
+					bad.Add(1)
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 50; i++ {
+		s.write("config.yaml", reloadConfig(i))
+		if err := s.reload(); err != nil {
+			t.Fatalf("reload %d: %v", i, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if n := bad.Load(); n > 0 {
+		t.Fatalf("%d responses mixed up versions or failed during reload", n)
+	}
+}
+
+func slowReloadConfig(version int) string {
+	return fmt.Sprintf(`
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /slow
+        method: GET
+        delay: 5ms
+        responseBody: v%d
+`, version)
+}
+
+// 经过真实的 HTTP 服务持续重载，请求跨越路由切换时也不能出现 5xx 或连接错误
+func TestReloadStressNoServerErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	s := newTestServer(t, map[string]string{"config.yaml": slowReloadConfig(0)}, routerOptions{})
+	srv := httptest.NewServer(s.handler)
+	defer srv.Close()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var requests, failures atomic.Int64
+	errs := make(chan error, 1)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				resp, err := srv.Client().Get(srv.URL + "/slow")
+				requests.Add(1)
+				if err != nil {
+					failures.Add(1)
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				resp.Body.Close()
+				if resp.StatusCode >= 500 {
+					failures.Add(1)
+					select {
+					case errs <- fmt.Errorf("status %d", resp.StatusCode):
+					default:
+					}
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 200; i++ {
+		s.write("config.yaml", slowReloadConfig(i))
+		if err := s.reload(); err != nil {
+			t.Fatalf("reload %d: %v", i, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if n := failures.Load(); n > 0 {
+		t.Fatalf("%d of %d requests failed during reloads, first: %v", n, requests.Load(), <-errs)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDKey 是请求 ID 在 gin 上下文中的键
+const requestIDKey = "mock.requestID"
+
+// newRequestID 生成一个随机的 UUID v4
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// requestIDMiddleware 沿用客户端传入的请求 ID，没有时生成一个。
+// 请求 ID 会写回请求头（转发到 upstream 时一并带上）和响应头
+func requestIDMiddleware(header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(header)
+		if id == "" {
+			id = newRequestID()
+			c.Request.Header.Set(header, id)
+		}
+		c.Set(requestIDKey, id)
+		c.Header(header, id)
+		c.Next()
+	}
+}
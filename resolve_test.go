@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 相对的 responseFile 和 include 路径按配置文件所在目录解析，从任意工作目录启动都能加载
+func TestRelativePathsIndependentOfWorkingDir(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"config.yaml": `
+include:
+  - services/orders.yaml
+services:
+  - name: users
+    basePath: /users
+    endpoints:
+      - path: /list
+        method: GET
+        responseFile: mocks/users.json
+`,
+		"services/orders.yaml": `
+services:
+  - name: orders
+    basePath: /orders
+    endpoints:
+      - path: /list
+        method: GET
+        responseFile: orders.json
+`,
+		"mocks/users.json":     `{"users":[]}`,
+		"services/orders.json": `{"orders":[]}`,
+	})
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadConfig(filepath.Join(dir, "config.yaml"), loadOptions{})
+	if err != nil {
+		t.Fatalf("load from another working directory: %v", err)
+	}
+	engine, err := setupRouter(config, routerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for target, body := range map[string]string{"/users/list": `{"users":[]}`, "/orders/list": `{"orders":[]}`} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		expectStatus(t, w, 200)
+		expectBody(t, w, body)
+	}
+	// 监控的也是解析后的绝对路径
+	for _, file := range responseFiles(config) {
+		if !filepath.IsAbs(file) {
+			t.Fatalf("watched response file %s is not absolute", file)
+		}
+	}
+}
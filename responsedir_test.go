@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestServiceResponseDir(t *testing.T) {
+	abs := filepath.Join(t.TempDir(), "shared.json")
+	if err := os.WriteFile(abs, []byte(`{"from":"absolute"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t, map[string]string{
+		"config.yaml": `
+services:
+  - name: users
+    basePath: /users
+    responseDir: fixtures/users
+    endpoints:
+      - path: /list
+        method: GET
+        responseFile: list.json
+      - path: /shared
+        method: GET
+        responseFile: ` + abs + `
+  - name: plain
+    basePath: /plain
+    endpoints:
+      - path: /list
+        method: GET
+        responseFile: fixtures/plain.json
+`,
+		"fixtures/users/list.json": `{"from":"responseDir"}`,
+		"fixtures/plain.json":      `{"from":"configDir"}`,
+	}, routerOptions{})
+	expectBody(t, s.get("/users/list"), `{"from":"responseDir"}`)
+	expectBody(t, s.get("/users/shared"), `{"from":"absolute"}`)
+	expectBody(t, s.get("/plain/list"), `{"from":"configDir"}`)
+
+	// 监听的是解析后的路径，修改文件后的内容在下次请求时生效
+	watched := responseFiles(s.handler.activeConfig())
+	for _, want := range []string{filepath.Join(s.dir, "fixtures/users/list.json"), abs, filepath.Join(s.dir, "fixtures/plain.json")} {
+		if !slices.Contains(watched, want) {
+			t.Fatalf("watched files %v do not include %s", watched, want)
+		}
+	}
+	s.write("fixtures/users/list.json", `{"from":"updated"}`)
+	expectBody(t, s.get("/users/list"), `{"from":"updated"}`)
+}
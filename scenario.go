@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultScenarioTTL           = 30 * time.Minute
+	defaultScenarioSessionHeader = "X-Session-Id"
+)
+
+// Scenario 定义一个按会话区分的状态机，多个端点可以共享同一个场景。
+// 会话由 sessionHeader 或 sessionCookie 标识，请求未携带时归入同一个匿名会话
+type Scenario struct {
+	Name          string   `yaml:"name" json:"name" toml:"name"`
+	InitialState  string   `yaml:"initialState" json:"initialState" toml:"initialState"`
+	SessionHeader string   `yaml:"sessionHeader" json:"sessionHeader" toml:"sessionHeader"`
+	SessionCookie string   `yaml:"sessionCookie" json:"sessionCookie" toml:"sessionCookie"`
+	TTL           Duration `yaml:"ttl" json:"ttl" toml:"ttl"`
+}
+
+// EndpointScenario 描述端点在场景各状态下的响应，以及响应后要切换到的状态
+type EndpointScenario struct {
+	Name   string          `yaml:"name" json:"name" toml:"name"`
+	States []ScenarioState `yaml:"states" json:"states" toml:"states"`
+}
+
+// ScenarioState 是端点在某个场景状态下的响应，Next 为空时保持当前状态
+type ScenarioState struct {
+	State        string `yaml:"state" json:"state" toml:"state"`
+	Next         string `yaml:"next" json:"next" toml:"next"`
+	StatusCode   int    `yaml:"statusCode" json:"statusCode" toml:"statusCode"`
+	ResponseFile string `yaml:"responseFile" json:"responseFile" toml:"responseFile"`
+	ResponseBody string `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+}
+
+func (s Scenario) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("scenario has no name")
+	}
+	if s.InitialState == "" {
+		return fmt.Errorf("scenario %s has no initialState", s.Name)
+	}
+	if s.SessionHeader != "" && s.SessionCookie != "" {
+		return fmt.Errorf("scenario %s sets both sessionHeader and sessionCookie", s.Name)
+	}
+	if s.TTL < 0 {
+		return fmt.Errorf("scenario %s has a negative ttl", s.Name)
+	}
+	return nil
+}
+
+func (s Scenario) ttl() time.Duration {
+	if s.TTL > 0 {
+		return time.Duration(s.TTL)
+	}
+	return defaultScenarioTTL
+}
+
+func (s Scenario) sessionID(c *gin.Context) string {
+	if s.SessionCookie != "" {
+		id, _ := c.Cookie(s.SessionCookie)
+		return id
+	}
+	header := s.SessionHeader
+	if header == "" {
+		header = defaultScenarioSessionHeader
+	}
+	return c.GetHeader(header)
+}
+
+type scenarioSession struct {
+	state   string
+	expires time.Time
+}
+
+type scenarioKey struct {
+	name, session string
+}
+
+// scenarioRegistry 保存所有场景会话的当前状态。与 sequences 一样独立于路由引擎，
+// 重载配置后不会被重置；会话超过 TTL 未访问时回到初始状态
+type scenarioRegistry struct {
+	mu        sync.Mutex
+	sessions  map[scenarioKey]*scenarioSession
+	lastSweep time.Time
+}
+
+var scenarios = &scenarioRegistry{sessions: make(map[scenarioKey]*scenarioSession)}
+
+// advance 取出会话的当前状态，在 states 中找到对应项后按其 Next 切换状态。
+// 当前状态在 states 中没有对应项时返回 false，状态不变
+func (r *scenarioRegistry) advance(def Scenario, sessionID string, states []ScenarioState) (ScenarioState, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.sweep(now)
+	key := scenarioKey{def.Name, sessionID}
+	session, ok := r.sessions[key]
+	if !ok || now.After(session.expires) {
+		session = &scenarioSession{state: def.InitialState}
+		r.sessions[key] = session
+	}
+	session.expires = now.Add(def.ttl())
+
+	for _, s := range states {
+		if s.State == session.state {
+			if s.Next != "" {
+				session.state = s.Next
+			}
+			return s, true
+		}
+	}
+	return ScenarioState{}, false
+}
+
+// sweep 每分钟最多清理一次过期会话，避免会话数量无限增长
+func (r *scenarioRegistry) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < time.Minute {
+		return
+	}
+	r.lastSweep = now
+	for key, session := range r.sessions {
+		if now.After(session.expires) {
+			delete(r.sessions, key)
+		}
+	}
+}
+
+// reset 删除与 name/session 匹配的会话，参数为空表示不限制，返回删除的数量
+func (r *scenarioRegistry) reset(name, sessionID string, allSessions bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for key := range r.sessions {
+		if (name != "" && key.name != name) || (!allSessions && key.session != sessionID) {
+			continue
+		}
+		delete(r.sessions, key)
+		count++
+	}
+	return count
+}
+
+// scenarioPlayer 根据会话的当前状态选择端点响应
+type scenarioPlayer struct {
+	def           Scenario
+	states        []ScenarioState
+	defaultStatus int
+}
+
+func newScenarioPlayer(config *Config, endpoint Endpoint, defaultStatus int) *scenarioPlayer {
+	if endpoint.Scenario == nil {
+		return nil
+	}
+	for _, def := range config.Scenarios {
+		if def.Name == endpoint.Scenario.Name {
+			return &scenarioPlayer{def: def, states: endpoint.Scenario.States, defaultStatus: defaultStatus}
+		}
+	}
+	return nil
+}
+
+func (p *scenarioPlayer) next(c *gin.Context) (mockResponse, bool) {
+	s, ok := scenarios.advance(p.def, p.def.sessionID(c), p.states)
+	if !ok {
+		return mockResponse{}, false
+	}
+	statusCode := s.StatusCode
+	if statusCode == 0 {
+		statusCode = p.defaultStatus
+	}
+	return mockResponse{
+		file:       s.ResponseFile,
+		body:       []byte(s.ResponseBody),
+		statusCode: statusCode,
+		rule:       "scenario " + p.def.Name + " state " + s.State,
+	}, true
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonSchema 按 JSON Schema 的常用子集校验数据：type、enum、const、properties、required、
+// additionalProperties、items、数值和长度限制、pattern、allOf/anyOf/oneOf/not，
+// 以及文档内部的 $ref（例如 #/definitions/User 或 #/$defs/User）
+type jsonSchema struct {
+	// 复用 OpenAPI 导入中的 $ref 解析
+	doc *openAPISpec
+}
+
+func loadJSONSchema(file string) (*jsonSchema, error) {
+	data, err := readJSONFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+	return &jsonSchema{doc: &openAPISpec{root: root}}, nil
+}
+
+// validate 返回所有不符合 schema 的位置，按 JSON Pointer 标注
+func (s *jsonSchema) validate(value interface{}) []string {
+	var errs []string
+	s.check(s.doc.root, value, "", &errs, 0)
+	return errs
+}
+
+func (s *jsonSchema) check(schemaValue, value interface{}, pointer string, errs *[]string, depth int) {
+	if depth > 64 {
+		return
+	}
+	if b, ok := schemaValue.(bool); ok {
+		if !b {
+			*errs = append(*errs, fmt.Sprintf("%s: not allowed", displayPointer(pointer)))
+		}
+		return
+	}
+	schema, ok := s.doc.resolve(schemaValue).(map[string]interface{})
+	if !ok {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, displayPointer(pointer)+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesAnyType(value, types) {
+		fail("expected %s, got %s", strings.Join(types, " or "), jsonTypeOf(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsJSON(enum, value) {
+		fail("value %s is not one of the enum values", compactJSON(value))
+	}
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		fail("expected constant %s, got %s", compactJSON(constant), compactJSON(value))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if key, ok := name.(string); ok {
+					if _, present := v[key]; !present {
+						fail("missing required property %q", key)
+					}
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := pointer + "/" + escapePointer(key)
+			if prop, ok := props[key]; ok {
+				s.check(prop, v[key], child, errs, depth+1)
+				continue
+			}
+			if additional, ok := schema["additionalProperties"]; ok {
+				if allowed, isBool := additional.(bool); isBool && !allowed {
+					fail("unexpected property %q", key)
+					continue
+				}
+				s.check(additional, v[key], child, errs, depth+1)
+			}
+		}
+	case []interface{}:
+		if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < n {
+			fail("expected at least %v items, got %d", n, len(v))
+		}
+		if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(v)) > n {
+			fail("expected at most %v items, got %d", n, len(v))
+		}
+		if items, ok := schema["items"]; ok {
+			for i, item := range v {
+				s.check(items, item, pointer+"/"+strconv.Itoa(i), errs, depth+1)
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if n, ok := schemaNumber(schema, "minLength"); ok && length < n {
+			fail("expected length >= %v, got %v", n, length)
+		}
+		if n, ok := schemaNumber(schema, "maxLength"); ok && length > n {
+			fail("expected length <= %v, got %v", n, length)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				fail("invalid pattern %q in schema: %v", pattern, err)
+			} else if !re.MatchString(v) {
+				fail("%q does not match pattern %q", v, pattern)
+			}
+		}
+	case float64:
+		if n, ok := schemaNumber(schema, "minimum"); ok && v < n {
+			fail("%v is less than minimum %v", v, n)
+		}
+		if n, ok := schemaNumber(schema, "maximum"); ok && v > n {
+			fail("%v is greater than maximum %v", v, n)
+		}
+		if n, ok := schemaNumber(schema, "exclusiveMinimum"); ok && v <= n {
+			fail("%v is not greater than %v", v, n)
+		}
+		if n, ok := schemaNumber(schema, "exclusiveMaximum"); ok && v >= n {
+			fail("%v is not less than %v", v, n)
+		}
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			s.check(sub, value, pointer, errs, depth+1)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && s.countMatches(anyOf, value, pointer, depth) == 0 {
+		fail("does not match any schema in anyOf")
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if n := s.countMatches(oneOf, value, pointer, depth); n != 1 {
+			fail("matches %d schemas in oneOf, expected exactly 1", n)
+		}
+	}
+	if not, ok := schema["not"]; ok {
+		var sub []string
+		s.check(not, value, pointer, &sub, depth+1)
+		if len(sub) == 0 {
+			fail("must not match the schema in not")
+		}
+	}
+}
+
+func (s *jsonSchema) countMatches(schemas []interface{}, value interface{}, pointer string, depth int) int {
+	n := 0
+	for _, sub := range schemas {
+		var errs []string
+		s.check(sub, value, pointer, &errs, depth+1)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func schemaTypes(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesAnyType(value interface{}, types []string) bool {
+	actual := jsonTypeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf 返回 JSON Schema 中的类型名，没有小数部分的数字视为 integer
+func jsonTypeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	n, ok := schema[key].(float64)
+	return n, ok
+}
+
+func containsJSON(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if jsonEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEqual 比较两个解码后的 JSON 值，map 编码时键已排序，可以直接比较编码结果
+func jsonEqual(a, b interface{}) bool {
+	return compactJSON(a) == compactJSON(b)
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func displayPointer(pointer string) string {
+	if pointer == "" {
+		return "(root)"
+	}
+	return pointer
+}
+
+// unsupportedSchemaKeywords 是常见但不会被校验的关键字，加载时给出警告，避免误以为它们已生效
+var unsupportedSchemaKeywords = []string{"format", "uniqueItems", "multipleOf", "patternProperties", "prefixItems"}
+
+// unsupportedKeywords 遍历 schema 中所有子 schema，返回用到的不支持的关键字（已排序、去重）。
+// draft-04 中布尔形式的 exclusiveMinimum/exclusiveMaximum 同样不支持
+func unsupportedKeywords(root map[string]interface{}) []string {
+	found := make(map[string]bool)
+	var walk func(v interface{}, depth int)
+	walkEach := func(v interface{}, depth int) {
+		switch t := v.(type) {
+		case []interface{}:
+			for _, item := range t {
+				walk(item, depth)
+			}
+		case map[string]interface{}:
+			for _, item := range t {
+				walk(item, depth)
+			}
+		}
+	}
+	walk = func(v interface{}, depth int) {
+		schema, ok := v.(map[string]interface{})
+		if !ok || depth > 64 {
+			return
+		}
+		for _, keyword := range unsupportedSchemaKeywords {
+			if _, ok := schema[keyword]; ok {
+				found[keyword] = true
+			}
+		}
+		for _, keyword := range []string{"exclusiveMinimum", "exclusiveMaximum"} {
+			if _, ok := schema[keyword].(bool); ok {
+				found[keyword+" (boolean)"] = true
+			}
+		}
+		for _, keyword := range []string{"items", "additionalProperties", "not"} {
+			walk(schema[keyword], depth+1)
+		}
+		for _, keyword := range []string{"properties", "patternProperties", "definitions", "$defs", "prefixItems", "allOf", "anyOf", "oneOf"} {
+			walkEach(schema[keyword], depth+1)
+		}
+		// draft-04 的 items 也可以是数组
+		if items, ok := schema["items"].([]interface{}); ok {
+			walkEach(items, depth+1)
+		}
+	}
+	walk(root, 0)
+	keywords := make([]string, 0, len(found))
+	for keyword := range found {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// schemaFixture 是一份需要按 schema 校验的响应内容，file 为空时 body 是内联的响应体
+type schemaFixture struct {
+	name string
+	file string
+	body string
+}
+
+// schemaFixtures 返回端点中需要按 schema 校验的响应：默认响应（responseFile、通配符匹配的文件或 responseBody）
+// 以及匹配规则、序列、加权响应、场景状态和 JSON 类型的内容协商变体。
+// 显式声明 4xx/5xx 状态码的响应通常是另一种错误结构，不参与校验
+func schemaFixtures(endpoint Endpoint) []schemaFixture {
+	var fixtures []schemaFixture
+	add := func(name string, status int, file, body string) {
+		if status >= 400 {
+			return
+		}
+		switch {
+		case file != "":
+			fixtures = append(fixtures, schemaFixture{name: file, file: file})
+		case body != "":
+			fixtures = append(fixtures, schemaFixture{name: name, body: body})
+		}
+	}
+	if len(endpoint.globFiles) > 0 {
+		for _, file := range endpoint.globFiles {
+			add("", endpoint.StatusCode, file, "")
+		}
+	} else {
+		add("responseBody", endpoint.StatusCode, endpoint.ResponseFile, endpoint.ResponseBody)
+	}
+	for i, rule := range endpoint.Matches {
+		add(fmt.Sprintf("matches[%d].responseBody", i), rule.StatusCode, rule.ResponseFile, rule.ResponseBody)
+	}
+	for i, step := range endpoint.Sequence {
+		add(fmt.Sprintf("sequence[%d].responseBody", i), step.StatusCode, step.ResponseFile, step.ResponseBody)
+	}
+	for i, resp := range endpoint.Responses {
+		add(fmt.Sprintf("responses[%d].responseBody", i), resp.StatusCode, resp.ResponseFile, resp.ResponseBody)
+	}
+	if endpoint.Scenario != nil {
+		for i, state := range endpoint.Scenario.States {
+			add(fmt.Sprintf("scenario.states[%d].responseBody", i), state.StatusCode, state.ResponseFile, state.ResponseBody)
+		}
+	}
+	for i, variant := range endpoint.Variants {
+		if isJSONContentType(variant.MediaType) {
+			add(fmt.Sprintf("variants[%d].responseBody", i), variant.StatusCode, variant.ResponseFile, variant.ResponseBody)
+		}
+	}
+	return fixtures
+}
+
+// validateSchemas 用端点的 schema 校验 schemaFixtures 返回的所有响应，并一次性列出所有不符合的位置。
+// 模板端点渲染前的内容不是最终响应，跳过校验
+func validateSchemas(config *Config) error {
+	var invalid []string
+	for _, service := range config.Services {
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Schema == "" {
+				continue
+			}
+			fullPath := service.BasePath + endpoint.Path
+			where := fmt.Sprintf("endpoint %s %s in service %s", endpoint.Method, fullPath, service.Name)
+			if endpoint.Template {
+				log.Printf("Warning: %s is a template, schema %s is not checked", where, endpoint.Schema)
+				continue
+			}
+			schema, err := loadJSONSchema(endpoint.Schema)
+			if err != nil {
+				return fmt.Errorf("%s: schema %s: %v", where, endpoint.Schema, err)
+			}
+			if keywords := unsupportedKeywords(schema.doc.root); len(keywords) > 0 {
+				log.Printf("Warning: schema %s of %s uses unsupported keywords %s, they are not checked", endpoint.Schema, where, strings.Join(keywords, ", "))
+			}
+
+			for _, f := range schemaFixtures(endpoint) {
+				data := []byte(f.body)
+				if f.file != "" {
+					if data, err = readJSONFile(f.file); err != nil {
+						// 缺失的文件已由 validateResponseFiles 报告或交给 fallback 处理
+						continue
+					}
+				}
+				var value interface{}
+				if err := json.Unmarshal(data, &value); err != nil {
+					invalid = append(invalid, fmt.Sprintf("%s (%s): invalid JSON: %v", f.name, where, err))
+					continue
+				}
+				for _, e := range schema.validate(value) {
+					invalid = append(invalid, fmt.Sprintf("%s (%s): %s", f.name, where, e))
+				}
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("response fixtures do not match schema:\n  %s", strings.Join(invalid, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const userSchema = `{
+  "type": "object",
+  "required": ["id"],
+  "properties": {
+    "id": {"type": "integer"},
+    "format": {"type": "string", "format": "email"},
+    "tags": {"type": "array", "uniqueItems": true}
+  },
+  "definitions": {
+    "Price": {"type": "number", "multipleOf": 0.01, "exclusiveMinimum": true, "minimum": 0}
+  }
+}`
+
+func TestUnsupportedSchemaKeywords(t *testing.T) {
+	schema := loadTestSchema(t, userSchema)
+	want := []string{"exclusiveMinimum (boolean)", "format", "multipleOf", "uniqueItems"}
+	if got := unsupportedKeywords(schema.doc.root); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unsupportedKeywords = %v, want %v", got, want)
+	}
+}
+
+func loadTestSchema(t *testing.T, content string) *jsonSchema {
+	t.Helper()
+	dir := writeTestFiles(t, map[string]string{"schema.json": content})
+	schema, err := loadJSONSchema(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func schemaEndpointConfig(endpoint string) map[string]string {
+	return map[string]string{
+		"config.yaml": `
+scenarios:
+  - name: flow
+    initialState: start
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /user
+        method: GET
+        schema: schema.json
+        responseBody: '{"id": 1}'
+` + endpoint,
+		"schema.json": userSchema,
+		"bad.json":    `{"id": "two"}`,
+	}
+}
+
+func TestSchemaChecksAllFixtures(t *testing.T) {
+	cases := map[string]string{
+		"match": `
+        matches:
+          - when:
+              query:
+                v: "2"
+            responseBody: '{"name": "no id"}'`,
+		"sequence": `
+        sequence:
+          - responseBody: '{"id": 1}'
+          - responseFile: bad.json`,
+		"variant": `
+        variants:
+          - mediaType: application/vnd.api+json
+            responseBody: '{}'`,
+		"scenario": `
+        scenario:
+          name: flow
+          states:
+            - state: start
+              responseBody: '{"id": 1.5}'`,
+	}
+	for name, endpoint := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := writeTestFiles(t, schemaEndpointConfig(endpoint))
+			_, err := loadConfig(filepath.Join(dir, "config.yaml"), loadOptions{})
+			if err == nil || !strings.Contains(err.Error(), "do not match schema") {
+				t.Fatalf("err = %v, want a schema mismatch", err)
+			}
+		})
+	}
+}
+
+// 非 JSON 的变体和显式声明错误状态码的响应不按 schema 校验
+func TestSchemaSkipsNonJSONAndErrorFixtures(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(io.Discard)
+
+	dir := writeTestFiles(t, schemaEndpointConfig(`
+        matches:
+          - when:
+              query:
+                missing: "1"
+            statusCode: 404
+            responseBody: '{"error": "not found"}'
+        variants:
+          - mediaType: application/json
+            responseBody: '{"id": 2}'
+          - mediaType: text/plain
+            responseBody: plain text`))
+	if _, err := loadConfig(filepath.Join(dir, "config.yaml"), loadOptions{}); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !strings.Contains(buf.String(), "unsupported keywords exclusiveMinimum (boolean), format, multipleOf, uniqueItems") {
+		t.Fatalf("log %q does not warn about unsupported keywords", buf.String())
+	}
+}
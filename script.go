@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/gin-gonic/gin"
+)
+
+// scriptRequest 是脚本中可以访问的请求信息。headers 的键统一为小写，
+// json 是按 JSON 解析后的请求体，请求体不是 JSON 时为 nil
+type scriptRequest struct {
+	Method  string            `expr:"method"`
+	Path    string            `expr:"path"`
+	Query   map[string]string `expr:"query"`
+	Headers map[string]string `expr:"headers"`
+	Params  map[string]string `expr:"params"`
+	Body    string            `expr:"body"`
+	JSON    interface{}       `expr:"json"`
+}
+
+type scriptEnv struct {
+	Request scriptRequest `expr:"request"`
+}
+
+// compileScript 在加载时编译端点脚本。脚本只能读取 request，表达式语言本身没有 I/O 能力
+func compileScript(script string) (*vm.Program, error) {
+	return expr.Compile(script, expr.Env(scriptEnv{}))
+}
+
+// scriptRunner 执行端点脚本并把结果转换为响应。脚本返回 nil 表示交给声明式配置处理，
+// 返回字符串表示 responseFile，返回 map 时可以包含 status、file 和 body
+type scriptRunner struct {
+	program     *vm.Program
+	responseDir string
+	statusCode  int
+}
+
+func newScriptRunner(service Service, endpoint Endpoint, statusCode int) (*scriptRunner, error) {
+	if endpoint.Script == "" {
+		return nil, nil
+	}
+	program, err := compileScript(endpoint.Script)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptRunner{program: program, responseDir: service.ResponseDir, statusCode: statusCode}, nil
+}
+
+func (s *scriptRunner) env(c *gin.Context) (scriptEnv, error) {
+	req := scriptRequest{
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+		Query:   make(map[string]string),
+		Headers: make(map[string]string),
+		Params:  make(map[string]string),
+	}
+	for k, v := range c.Request.URL.Query() {
+		req.Query[k] = v[0]
+	}
+	for k, v := range c.Request.Header {
+		req.Headers[strings.ToLower(k)] = v[0]
+	}
+	for _, p := range c.Params {
+		req.Params[p.Key] = p.Value
+	}
+	body, err := readRequestBody(c)
+	if err != nil {
+		return scriptEnv{}, err
+	}
+	req.Body = string(body)
+	if len(body) > 0 && isJSONContentType(c.GetHeader("Content-Type")) {
+		json.Unmarshal(body, &req.JSON)
+	}
+	return scriptEnv{Request: req}, nil
+}
+
+// run 返回脚本选择的响应，ok 为 false 表示脚本没有给出结果
+func (s *scriptRunner) run(c *gin.Context) (resp mockResponse, ok bool, err error) {
+	env, err := s.env(c)
+	if err != nil {
+		return resp, false, err
+	}
+	out, err := expr.Run(s.program, env)
+	if err != nil {
+		return resp, false, err
+	}
+	resp = mockResponse{statusCode: s.statusCode, rule: "script"}
+	switch v := out.(type) {
+	case nil:
+		return resp, false, nil
+	case string:
+		resp.file = s.resolve(v)
+	case map[string]interface{}:
+		if status, ok := v["status"]; ok {
+			code, ok := status.(int)
+			if f, isFloat := status.(float64); isFloat {
+				code, ok = int(f), true
+			}
+			if !ok || code < 100 || code > 999 {
+				return resp, false, fmt.Errorf("script returned invalid status %v", status)
+			}
+			resp.statusCode = code
+		}
+		if file, ok := v["file"].(string); ok {
+			resp.file = s.resolve(file)
+		}
+		switch body := v["body"].(type) {
+		case nil:
+		case string:
+			resp.body = []byte(body)
+		default:
+			// 非字符串的 body 按 JSON 编码，便于直接返回对象
+			if resp.body, err = json.Marshal(body); err != nil {
+				return resp, false, fmt.Errorf("script returned a body that cannot be encoded as JSON: %v", err)
+			}
+		}
+	default:
+		return resp, false, fmt.Errorf("script returned %T, expected nil, a file name or a map with status/file/body", out)
+	}
+	return resp, true, nil
+}
+
+// resolve 与配置中的 responseFile 一样，相对路径相对于服务的 responseDir
+func (s *scriptRunner) resolve(file string) string {
+	return resolvePath(s.responseDir, file)
+}
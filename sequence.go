@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SequenceStep 是 sequence 中的一步，连续请求依次返回各步的响应
+type SequenceStep struct {
+	StatusCode   int    `yaml:"statusCode" json:"statusCode" toml:"statusCode"`
+	ResponseFile string `yaml:"responseFile" json:"responseFile" toml:"responseFile"`
+	ResponseBody string `yaml:"responseBody" json:"responseBody" toml:"responseBody"`
+}
+
+const (
+	sequenceModeStick = "stick"
+	sequenceModeWrap  = "wrap"
+)
+
+// sequenceState 记录某个端点下一次应返回的步骤
+type sequenceState struct {
+	mu   sync.Mutex
+	next int
+}
+
+// sequenceRegistry 按 routeKey（"METHOD path"，带 host 或 port 时附加在后）保存所有序列端点的状态。
+// 状态独立于路由引擎，重载配置后不会被重置，只能通过管理接口重置
+type sequenceRegistry struct {
+	mu     sync.Mutex
+	states map[string]*sequenceState
+}
+
+var sequences = &sequenceRegistry{states: make(map[string]*sequenceState)}
+
+func (r *sequenceRegistry) get(key string) *sequenceState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.states[key]
+	if !ok {
+		state = &sequenceState{}
+		r.states[key] = state
+	}
+	return state
+}
+
+// reset 重置与 method/path 匹配的序列，参数为空表示不限制，返回重置的数量
+func (r *sequenceRegistry) reset(method, path string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for key, state := range r.states {
+		if !routeKeyMatches(key, method, path) {
+			continue
+		}
+		state.mu.Lock()
+		state.next = 0
+		state.mu.Unlock()
+		count++
+	}
+	return count
+}
+
+// sequencePlayer 依次返回序列中的响应，到达末尾后根据 mode 回到开头或停在最后一步
+type sequencePlayer struct {
+	state     *sequenceState
+	responses []mockResponse
+	wrap      bool
+}
+
+func newSequencePlayer(key string, steps []SequenceStep, mode string, defaultStatus int) *sequencePlayer {
+	if len(steps) == 0 {
+		return nil
+	}
+	p := &sequencePlayer{state: sequences.get(key), wrap: mode == sequenceModeWrap}
+	for i, step := range steps {
+		statusCode := step.StatusCode
+		if statusCode == 0 {
+			statusCode = defaultStatus
+		}
+		p.responses = append(p.responses, mockResponse{
+			file:       step.ResponseFile,
+			body:       []byte(step.ResponseBody),
+			statusCode: statusCode,
+			rule:       fmt.Sprintf("sequence[%d]", i),
+		})
+	}
+	return p
+}
+
+func (p *sequencePlayer) next() mockResponse {
+	p.state.mu.Lock()
+	defer p.state.mu.Unlock()
+
+	i := p.state.next
+	if i >= len(p.responses) {
+		if p.wrap {
+			i = i % len(p.responses)
+		} else {
+			i = len(p.responses) - 1
+		}
+	}
+	p.state.next = i + 1
+	if p.wrap {
+		p.state.next %= len(p.responses)
+	}
+	return p.responses[i]
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordedCall 是请求记录器中保存的一次调用
+type recordedCall struct {
+	Time    time.Time   `json:"time"`
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// callRecorder 在内存中保存最近的 max 次调用，超出后丢弃最早的记录
+type callRecorder struct {
+	mu    sync.Mutex
+	max   int
+	calls []recordedCall
+}
+
+// validateMaxRecordedCalls 检查 -max-recorded-calls，0 表示不保留任何调用
+func validateMaxRecordedCalls(max int) error {
+	if max < 0 {
+		return fmt.Errorf("invalid -max-recorded-calls %d, expected 0 or more", max)
+	}
+	return nil
+}
+
+func newCallRecorder(max int) *callRecorder {
+	return &callRecorder{max: max}
+}
+
+func (r *callRecorder) add(call recordedCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+	if over := len(r.calls) - r.max; over > 0 {
+		r.calls = append(r.calls[:0:0], r.calls[over:]...)
+	}
+}
+
+// list 返回与 method/path 匹配的调用，参数为空表示不限制
+func (r *callRecorder) list(method, path string) []recordedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := []recordedCall{}
+	for _, call := range r.calls {
+		if method != "" && !strings.EqualFold(call.Method, method) {
+			continue
+		}
+		if path != "" && call.Path != path {
+			continue
+		}
+		result = append(result, call)
+	}
+	return result
+}
+
+func (r *callRecorder) reset() {
+	r.mu.Lock()
+	r.calls = nil
+	r.mu.Unlock()
+}
+
+func callRecorderMiddleware(r *callRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, _ := readRequestBody(c)
+		r.add(recordedCall{
+			Time:    time.Now(),
+			Method:  c.Request.Method,
+			Path:    c.Request.URL.Path,
+			Query:   c.Request.URL.RawQuery,
+			Headers: c.Request.Header.Clone(),
+			Body:    string(body),
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidateMaxRecordedCalls(t *testing.T) {
+	for max, ok := range map[int]bool{-1: false, 0: true, 1000: true} {
+		if err := validateMaxRecordedCalls(max); (err == nil) != ok {
+			t.Fatalf("validateMaxRecordedCalls(%d) = %v, want ok=%v", max, err, ok)
+		}
+	}
+}
+
+// 记录器只保留最近的 max 次调用，max 为 0 时不保留
+func TestCallRecorderKeepsLatest(t *testing.T) {
+	r := newCallRecorder(2)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		r.add(recordedCall{Method: "GET", Path: path})
+	}
+	calls := r.list("", "")
+	if len(calls) != 2 || calls[0].Path != "/b" || calls[1].Path != "/c" {
+		t.Fatalf("calls = %v, want /b and /c", calls)
+	}
+
+	empty := newCallRecorder(0)
+	empty.add(recordedCall{Method: "GET", Path: "/a"})
+	if calls := empty.list("", ""); len(calls) != 0 {
+		t.Fatalf("recorder with max 0 kept %v", calls)
+	}
+}
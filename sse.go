@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+// sseEvent 是 SSE 响应文件中的一个事件，data 可以是字符串或任意 JSON 值
+type sseEvent struct {
+	Event string      `json:"event" yaml:"event"`
+	ID    string      `json:"id" yaml:"id"`
+	Data  interface{} `json:"data" yaml:"data"`
+	Delay string      `json:"delay" yaml:"delay"`
+
+	delay time.Duration
+	data  string
+}
+
+// parseSSEEvents 解析事件列表，.json 文件按 JSON 解析，其余按 YAML 解析
+func parseSSEEvents(name string, content []byte) ([]sseEvent, error) {
+	var events []sseEvent
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = json.Unmarshal(content, &events)
+	} else {
+		err = yaml.Unmarshal(content, &events)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid sse events: %v", err)
+	}
+
+	for i := range events {
+		e := &events[i]
+		if e.Delay != "" {
+			if e.delay, err = time.ParseDuration(e.Delay); err != nil {
+				return nil, fmt.Errorf("invalid delay of sse event %d: %v", i, err)
+			}
+		}
+		switch data := e.Data.(type) {
+		case nil:
+		case string:
+			e.data = data
+		default:
+			encoded, err := json.Marshal(normalizeYAML(data))
+			if err != nil {
+				return nil, fmt.Errorf("invalid data of sse event %d: %v", i, err)
+			}
+			e.data = string(encoded)
+		}
+	}
+	return events, nil
+}
+
+// normalizeYAML 将 yaml.v2 解析出的 map[interface{}]interface{} 转换为可以 JSON 编码的结构
+func normalizeYAML(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeYAML(item)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// newSSEHandler 按顺序推送事件，每个事件发送后立即 flush，客户端断开时停止
+func newSSEHandler(hc handlerContext, endpoint Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		content := []byte(endpoint.ResponseBody)
+		if endpoint.ResponseFile != "" {
+			var err error
+			content, err = readJSONFile(endpoint.ResponseFile)
+			if err != nil {
+				hc.fileError(c, endpoint.ResponseFile, err)
+				return
+			}
+		}
+		events, err := parseSSEEvents(endpoint.ResponseFile, content)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		for k, v := range endpoint.Headers {
+			c.Header(k, v)
+		}
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		ctx := c.Request.Context()
+		for _, e := range events {
+			if e.delay > 0 {
+				timer := time.NewTimer(e.delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+			}
+
+			var buf strings.Builder
+			if e.ID != "" {
+				fmt.Fprintf(&buf, "id: %s\n", e.ID)
+			}
+			if e.Event != "" {
+				fmt.Fprintf(&buf, "event: %s\n", e.Event)
+			}
+			for _, line := range strings.Split(e.data, "\n") {
+				fmt.Fprintf(&buf, "data: %s\n", line)
+			}
+			buf.WriteString("\n")
+			if _, err := c.Writer.WriteString(buf.String()); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
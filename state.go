@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// stateSnapshotVersion 是状态快照的格式版本，格式不兼容地变化时递增
+const stateSnapshotVersion = 1
+
+// stateSnapshot 是 GET/PUT /__admin/state 使用的快照格式，包含所有跨请求、且在重载后保留的状态：
+//   - sequences：序列端点下一次返回的步骤序号，键为 routeKey
+//   - scenarios：场景会话的当前状态和过期时间
+//   - callCounts：callLimit 端点的累计调用次数，键为 routeKey
+//
+// 限流令牌桶、响应缓存、通配符文件的轮流计数等状态随路由引擎重建，重载后即重置，不在快照中
+type stateSnapshot struct {
+	Version    int                `json:"version"`
+	Sequences  map[string]int     `json:"sequences"`
+	Scenarios  []scenarioSnapshot `json:"scenarios"`
+	CallCounts map[string]int64   `json:"callCounts"`
+}
+
+type scenarioSnapshot struct {
+	Name    string `json:"name"`
+	Session string `json:"session"`
+	State   string `json:"state"`
+	// Expires 为空时按场景的 ttl 从恢复时刻重新计算
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+func (r *sequenceRegistry) snapshot() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]int, len(r.states))
+	for key, state := range r.states {
+		state.mu.Lock()
+		out[key] = state.next
+		state.mu.Unlock()
+	}
+	return out
+}
+
+// restore 用快照替换所有序列的位置。序列播放器持有状态对象的指针，
+// 所以这里只修改已有对象，快照中没有的序列回到开头
+func (r *sequenceRegistry) restore(positions map[string]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, state := range r.states {
+		state.mu.Lock()
+		state.next = positions[key]
+		state.mu.Unlock()
+	}
+	for key, next := range positions {
+		if _, ok := r.states[key]; !ok {
+			r.states[key] = &sequenceState{next: next}
+		}
+	}
+}
+
+func (r *scenarioRegistry) snapshot() []scenarioSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	out := []scenarioSnapshot{}
+	for key, session := range r.sessions {
+		if now.After(session.expires) {
+			continue
+		}
+		expires := session.expires
+		out = append(out, scenarioSnapshot{Name: key.name, Session: key.session, State: session.state, Expires: &expires})
+	}
+	return out
+}
+
+// restore 用快照替换所有场景会话，ttl 返回场景的会话有效期
+func (r *scenarioRegistry) restore(sessions []scenarioSnapshot, ttl func(name string) time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions = make(map[scenarioKey]*scenarioSession, len(sessions))
+	now := time.Now()
+	for _, s := range sessions {
+		expires := now.Add(ttl(s.Name))
+		if s.Expires != nil {
+			expires = *s.Expires
+		}
+		r.sessions[scenarioKey{s.Name, s.Session}] = &scenarioSession{state: s.State, expires: expires}
+	}
+}
+
+func takeStateSnapshot() stateSnapshot {
+	return stateSnapshot{
+		Version:    stateSnapshotVersion,
+		Sequences:  sequences.snapshot(),
+		Scenarios:  scenarios.snapshot(),
+		CallCounts: callCounters.snapshot(),
+	}
+}
+
+// restoreStateSnapshot 校验快照后整体替换状态，config 用于确定场景的 ttl
+func restoreStateSnapshot(snapshot stateSnapshot, config *Config) error {
+	if snapshot.Version != stateSnapshotVersion {
+		return fmt.Errorf("unsupported state snapshot version %d, expected %d", snapshot.Version, stateSnapshotVersion)
+	}
+	for key, next := range snapshot.Sequences {
+		if next < 0 {
+			return fmt.Errorf("sequence %s has a negative position", key)
+		}
+	}
+	for key, n := range snapshot.CallCounts {
+		if n < 0 {
+			return fmt.Errorf("call count of %s is negative", key)
+		}
+	}
+	for i, s := range snapshot.Scenarios {
+		if s.Name == "" || s.State == "" {
+			return fmt.Errorf("scenario session %d has no name or state", i)
+		}
+	}
+	sequences.restore(snapshot.Sequences)
+	callCounters.restore(snapshot.CallCounts)
+	scenarios.restore(snapshot.Scenarios, func(name string) time.Duration {
+		for _, def := range config.Scenarios {
+			if def.Name == name {
+				return def.ttl()
+			}
+		}
+		return defaultScenarioTTL
+	})
+	return nil
+}
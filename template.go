@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// templateContext 是模板渲染时可访问的请求数据
+type templateContext struct {
+	Params  map[string]string
+	Query   map[string]string
+	Headers map[string]string
+	Body    interface{}
+}
+
+func newTemplateContext(c *gin.Context) *templateContext {
+	ctx := &templateContext{
+		Params:  make(map[string]string),
+		Query:   make(map[string]string),
+		Headers: make(map[string]string),
+	}
+	for _, p := range c.Params {
+		ctx.Params[p.Key] = p.Value
+	}
+	for k, v := range c.Request.URL.Query() {
+		if len(v) > 0 {
+			ctx.Query[k] = v[0]
+		}
+	}
+	for k, v := range c.Request.Header {
+		if len(v) > 0 {
+			ctx.Headers[k] = v[0]
+		}
+	}
+	ctx.Body = parseJSONBody(c)
+	return ctx
+}
+
+var fakeNames = []string{
+	"Alice Johnson", "Bob Smith", "Carol Williams", "David Brown", "Emma Jones",
+	"Frank Miller", "Grace Davis", "Henry Wilson", "Isabel Moore", "Jack Taylor",
+	"Karen Anderson", "Liam Thomas", "Mia Jackson", "Noah White", "Olivia Harris",
+}
+
+// templateFuncs 是模板中可用的假数据函数，随机数都来自 rng，可通过 -seed 复现
+var templateFuncs = template.FuncMap{
+	"uuid": func() string {
+		var b [16]byte
+		for i := range b {
+			b[i] = byte(rng.Int63n(256))
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	},
+	"randInt": func(min, max int) (int, error) {
+		if max < min {
+			return 0, fmt.Errorf("randInt: max %d is less than min %d", max, min)
+		}
+		return min + int(rng.Int63n(int64(max-min)+1)), nil
+	},
+	"now": func(layout string) string {
+		return time.Now().Format(layout)
+	},
+	"randName": func() string {
+		return fakeNames[rng.Int63n(int64(len(fakeNames)))]
+	},
+}
+
+// templateFuncNames 按字母顺序列出可用的模板函数，用于错误提示
+func templateFuncNames() string {
+	names := make([]string, 0, len(templateFuncs))
+	for name := range templateFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// renderTemplate 渲染响应模板，partials 为 templateDir 中加载的模板，可以为 nil
+func renderTemplate(name string, data []byte, c *gin.Context, partials *template.Template) ([]byte, error) {
+	set, err := newTemplateSet(partials)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := set.New(name).Parse(string(data))
+	if err != nil {
+		if strings.Contains(err.Error(), "function") && strings.Contains(err.Error(), "not defined") {
+			return nil, fmt.Errorf("%v (available functions: %s)", err, templateFuncNames())
+		}
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, newTemplateContext(c)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// throttleInterval 是限速写入的节拍，每个节拍最多写出 1/10 秒的配额
+const throttleInterval = 100 * time.Millisecond
+
+// throttledWriter 按每秒字节数限速写出响应体，每写出一块就 flush，
+// 让客户端实际以该速度收到数据。客户端断开后立即返回 ctx 的错误
+type throttledWriter struct {
+	gin.ResponseWriter
+	ctx   context.Context
+	chunk int
+}
+
+func (w *throttledWriter) Write(data []byte) (int, error) {
+	written := 0
+	for len(data) > 0 {
+		n := w.chunk
+		if n > len(data) {
+			n = len(data)
+		}
+		m, err := w.ResponseWriter.Write(data[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		w.ResponseWriter.Flush()
+		data = data[n:]
+
+		timer := time.NewTimer(throttleInterval)
+		select {
+		case <-w.ctx.Done():
+			timer.Stop()
+			return written, w.ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return written, nil
+}
+
+func (w *throttledWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// throttleMiddleware 把响应体限制在 bytesPerSecond 以内，作用于 gzip 压缩前的内容
+func throttleMiddleware(bytesPerSecond int64) gin.HandlerFunc {
+	chunk := int(bytesPerSecond * int64(throttleInterval) / int64(time.Second))
+	if chunk < 1 {
+		chunk = 1
+	}
+	return func(c *gin.Context) {
+		c.Writer = &throttledWriter{ResponseWriter: c.Writer, ctx: c.Request.Context(), chunk: chunk}
+		c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func throttleConfig(size int) map[string]string {
+	return map[string]string{
+		"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /download
+        method: GET
+        responseFile: big.txt
+        throttle: 5000
+`,
+		"big.txt": strings.Repeat("x", size),
+	}
+}
+
+// 2000 字节按 5000 字节/秒写出，预计约 400ms
+func TestThrottleTakesExpectedTime(t *testing.T) {
+	s := newTestServer(t, throttleConfig(2000), routerOptions{})
+	start := time.Now()
+	w := s.get("/download")
+	elapsed := time.Since(start)
+	expectStatus(t, w, 200)
+	if w.Body.Len() != 2000 {
+		t.Fatalf("body length = %d, want 2000", w.Body.Len())
+	}
+	if elapsed < 300*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("throttled download took %v, want about 400ms", elapsed)
+	}
+}
+
+// 客户端断开后立即停止写出，而不是按限速写完整个响应体
+func TestThrottleStopsOnDisconnect(t *testing.T) {
+	s := newTestServer(t, throttleConfig(100000), routerOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/download", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	start := time.Now()
+	s.handler.ServeHTTP(w, req)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("handler returned after %v, want it to stop soon after the client left", elapsed)
+	}
+	if w.Body.Len() >= 100000 {
+		t.Fatalf("wrote the whole body after the client disconnected")
+	}
+}
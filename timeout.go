@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultTimeoutBody = `{"error":"gateway timeout"}`
+
+// timedOutKey 标记处理函数因超时被放弃，已经写出的部分响应不能被缓存
+const timedOutKey = "mock.timedOut"
+
+// withTimeout 为端点处理函数设置耗时上限。处理函数通过请求的 context 感知超时并放弃工作，
+// 超时时如果还没有写出响应则返回 504；已经开始输出的流式响应（如 SSE）会直接结束
+func withTimeout(timeout time.Duration, body string, next gin.HandlerFunc) gin.HandlerFunc {
+	if body == "" {
+		body = defaultTimeoutBody
+	}
+	return func(c *gin.Context) {
+		req := c.Request
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		c.Request = req.WithContext(ctx)
+
+		next(c)
+
+		// 恢复原来的请求，外层的缓存等中间件看到的仍是客户端连接的 context，而不是已取消的派生 context
+		c.Request = req
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.Set(timedOutKey, true)
+			if !c.Writer.Written() {
+				c.Data(http.StatusGatewayTimeout, "application/json", []byte(body))
+			}
+		}
+	}
+}
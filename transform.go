@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	transformSet    = "set"
+	transformRemove = "remove"
+)
+
+// TransformOp 是 transform 列表中的一个操作，按顺序作用于解析后的 JSON 响应。
+// set 将 path 处的值设为 value（缺少的对象字段会被创建），remove 删除 path 处的字段或数组元素
+type TransformOp struct {
+	Op    string      `yaml:"op" json:"op" toml:"op"`
+	Path  string      `yaml:"path" json:"path" toml:"path"`
+	Value interface{} `yaml:"value" json:"value" toml:"value"`
+}
+
+func (t TransformOp) validate() error {
+	if t.Op != transformSet && t.Op != transformRemove {
+		return fmt.Errorf("unsupported transform op %q, expected set or remove", t.Op)
+	}
+	steps, err := parseJSONPath(t.Path)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("transform path %q must not be the root", t.Path)
+	}
+	return nil
+}
+
+// applyPath 找到 steps 最后一段的父节点后调用 fn；create 为 true 时创建缺少的对象字段
+func applyPath(root interface{}, steps []jsonPathStep, create bool, fn func(parent interface{}, last jsonPathStep) bool) bool {
+	current := root
+	for _, step := range steps[:len(steps)-1] {
+		if step.key != "" {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			next, ok := obj[step.key]
+			if !ok {
+				if !create {
+					return false
+				}
+				next = make(map[string]interface{})
+				obj[step.key] = next
+			}
+			current = next
+			continue
+		}
+		arr, ok := current.([]interface{})
+		if !ok || step.index >= len(arr) {
+			return false
+		}
+		current = arr[step.index]
+	}
+	return fn(current, steps[len(steps)-1])
+}
+
+func setPath(root interface{}, steps []jsonPathStep, value interface{}) bool {
+	return applyPath(root, steps, true, func(parent interface{}, last jsonPathStep) bool {
+		if last.key != "" {
+			obj, ok := parent.(map[string]interface{})
+			if ok {
+				obj[last.key] = value
+			}
+			return ok
+		}
+		arr, ok := parent.([]interface{})
+		if !ok || last.index >= len(arr) {
+			return false
+		}
+		arr[last.index] = value
+		return true
+	})
+}
+
+// removePath 删除字段或数组元素；数组元素删除后需要写回父节点，因此返回新的根节点
+func removePath(root interface{}, steps []jsonPathStep) (interface{}, bool) {
+	if last := steps[len(steps)-1]; last.key == "" {
+		arr, ok := evalJSONPath(root, steps[:len(steps)-1])
+		list, isList := arr.([]interface{})
+		if !ok || !isList || last.index >= len(list) {
+			return root, false
+		}
+		trimmed := append(append([]interface{}{}, list[:last.index]...), list[last.index+1:]...)
+		if len(steps) == 1 {
+			return trimmed, true
+		}
+		return root, setPath(root, steps[:len(steps)-1], trimmed)
+	}
+	ok := applyPath(root, steps, false, func(parent interface{}, last jsonPathStep) bool {
+		obj, ok := parent.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, exists := obj[last.key]; !exists {
+			return false
+		}
+		delete(obj, last.key)
+		return true
+	})
+	return root, ok
+}
+
+// preparedTransform 是预先解析好路径的 transform 操作。value 保存为 JSON，
+// 每次请求重新解码，避免多个响应共享同一个可变对象
+type preparedTransform struct {
+	TransformOp
+	steps []jsonPathStep
+	value []byte
+}
+
+// prepareTransforms 在创建处理函数时解析路径和值，配置已在加载时校验
+func prepareTransforms(ops []TransformOp) ([]preparedTransform, error) {
+	prepared := make([]preparedTransform, 0, len(ops))
+	for _, op := range ops {
+		steps, err := parseJSONPath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(normalizeYAML(op.Value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid transform value at %s: %v", op.Path, err)
+		}
+		prepared = append(prepared, preparedTransform{TransformOp: op, steps: steps, value: value})
+	}
+	return prepared, nil
+}
+
+// unmarshalJSONNumbers 与 json.Unmarshal 相同，但数字解码为 json.Number，
+// 重新编码时原样输出，超过 float64 精度的整数 ID 不会被改写
+func unmarshalJSONNumbers(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	return nil
+}
+
+// transformJSON 依次执行 transform 操作，失败的操作跳过并在调试模式下记录日志
+func transformJSON(data []byte, ops []preparedTransform) ([]byte, error) {
+	var root interface{}
+	if err := unmarshalJSONNumbers(data, &root); err != nil {
+		return nil, fmt.Errorf("transform requires a JSON response: %v", err)
+	}
+	for i, op := range ops {
+		ok := false
+		switch op.Op {
+		case transformSet:
+			var value interface{}
+			unmarshalJSONNumbers(op.value, &value)
+			ok = setPath(root, op.steps, value)
+		case transformRemove:
+			root, ok = removePath(root, op.steps)
+		}
+		if !ok && gin.IsDebugging() {
+			log.Printf("Debug: transform %d (%s %s) skipped, path not found", i, op.Op, op.Path)
+		}
+	}
+	return json.Marshal(root)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTransformKeepsLargeIntegers(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"config.yaml": `
+services:
+  - name: svc
+    basePath: /
+    endpoints:
+      - path: /user
+        method: GET
+        responseFile: user.json
+        transform:
+          - op: set
+            path: $.name
+            value: bob
+          - op: set
+            path: $.big
+            value: 9007199254740995
+          - op: remove
+            path: $.secret
+`,
+		"user.json": `{"id": 9007199254740993, "ratio": 0.1, "name": "alice", "secret": "x"}`,
+	}, routerOptions{})
+	expectBody(t, s.get("/user"), `{"big":9007199254740995,"id":9007199254740993,"name":"bob","ratio":0.1}`)
+}
+
+func TestUnmarshalJSONNumbersRejectsTrailingData(t *testing.T) {
+	var v interface{}
+	if err := unmarshalJSONNumbers([]byte(`{"a":1} {"b":2}`), &v); err == nil {
+		t.Fatal("expected an error for trailing data")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEndpointWithoutResponse(t *testing.T) {
+	cases := map[string]bool{
+		"":                                false,
+		"echo: true":                      true,
+		"type: websocket":                 true,
+		"responseBodyBase64: aGk=":        true,
+		"sequence: [{responseBody: one}]": true,
+	}
+	for fields, ok := range cases {
+		dir := writeTestFiles(t, map[string]string{"config.yaml": `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /x
+        method: GET
+        ` + fields + "\n"})
+		_, err := loadConfig(filepath.Join(dir, "config.yaml"), loadOptions{})
+		if ok && err != nil {
+			t.Fatalf("%q: unexpected error %v", fields, err)
+		}
+		if !ok && (err == nil || !strings.Contains(err.Error(), "neither responseFile nor responseBody")) {
+			t.Fatalf("%q: err = %v, want missing response", fields, err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// version、commit 和 date 在构建时通过 ldflags 注入，例如：
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// 未注入时 version 为 dev
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// versionPath 是返回构建信息的管理接口，与其他管理接口一样只在 -admin 时提供
+const versionPath = "/__version"
+
+// buildInfo 是 -version 输出和 GET /__version 返回的构建信息
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+}
+
+func currentBuildInfo() buildInfo {
+	return buildInfo{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+}
+
+func (b buildInfo) String() string {
+	return fmt.Sprintf("easy-httpserver-mock %s (commit %s, built %s, %s)", b.Version, b.Commit, b.Date, b.GoVersion)
+}
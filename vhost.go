@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// virtualHosts 按请求的 Host 头把请求分派给对应的引擎。没有配置 host 的服务
+// 以及未知 host 的请求都由默认引擎处理，后者会得到全局的 404/notFound/upstream
+type virtualHosts struct {
+	engines  map[string]http.Handler
+	fallback http.Handler
+}
+
+func (v *virtualHosts) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	host := strings.ToLower(req.Host)
+	engine, ok := v.engines[host]
+	if !ok {
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			engine, ok = v.engines[name]
+		}
+	}
+	if !ok {
+		engine = v.fallback
+	}
+	engine.ServeHTTP(w, req)
+}
+
+// normalizeHost 统一 host 的大小写，host 可以带端口，带端口时只匹配该端口
+func normalizeHost(host string) (string, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if strings.ContainsAny(host, "/ ") {
+		return "", fmt.Errorf("invalid host %q, expected a host name such as api.example.com", host)
+	}
+	return host, nil
+}
+
+// setupRouter 构建路由。配置了 port 的服务按请求到达的端口使用各自的路由，
+// 同一端口内配置了 host 的服务再按 Host 头使用各自的引擎
+func setupRouter(config *Config, opts routerOptions) (http.Handler, error) {
+	if err := checkDuplicateRoutes(config); err != nil {
+		return nil, err
+	}
+	// 各个引擎使用的配置副本共享同一个缓存索引
+	config.caches = newCacheIndex()
+	var limiter *tokenBucket
+	if config.Server.RateLimit != nil {
+		limiter = newTokenBucket(config.Server.RateLimit)
+	}
+
+	ports := servicePorts(config)
+	if len(ports) == 0 {
+		return buildHosts(config, config.Services, opts, limiter)
+	}
+	p := &portRouter{handlers: make(map[int]http.Handler, len(ports))}
+	for _, port := range ports {
+		h, err := buildHosts(config, servicesOnPort(config, port), opts, limiter)
+		if err != nil {
+			return nil, fmt.Errorf("port %d: %v", port, err)
+		}
+		p.handlers[port] = h
+	}
+	fallback, err := buildHosts(config, servicesOnPort(config, 0), opts, limiter)
+	if err != nil {
+		return nil, err
+	}
+	p.fallback = fallback
+	return p, nil
+}
+
+// buildHosts 为一组服务构建路由，全部服务都没有 host 时只构建一个引擎
+func buildHosts(config *Config, services []Service, opts routerOptions, limiter *tokenBucket) (http.Handler, error) {
+	var defaults []Service
+	byHost := make(map[string][]Service)
+	var hosts []string
+	for _, service := range services {
+		if service.Host == "" {
+			defaults = append(defaults, service)
+			continue
+		}
+		if _, ok := byHost[service.Host]; !ok {
+			hosts = append(hosts, service.Host)
+		}
+		byHost[service.Host] = append(byHost[service.Host], service)
+	}
+	build := func(services []Service) (http.Handler, error) {
+		hostConfig := *config
+		hostConfig.Services = services
+		engine, err := buildEngine(&hostConfig, opts, limiter)
+		if err != nil {
+			return nil, err
+		}
+		return engine, nil
+	}
+	if len(hosts) == 0 {
+		return build(services)
+	}
+	v := &virtualHosts{engines: make(map[string]http.Handler, len(hosts))}
+	for _, host := range hosts {
+		engine, err := build(byHost[host])
+		if err != nil {
+			return nil, fmt.Errorf("host %s: %v", host, err)
+		}
+		v.engines[host] = engine
+		log.Printf("Virtual host %s: %d services", host, len(byHost[host]))
+	}
+	fallback, err := build(defaults)
+	if err != nil {
+		return nil, err
+	}
+	v.fallback = fallback
+	return v, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+)
+
+const twoHostSequenceConfig = `
+services:
+  - name: a
+    host: a.test
+    basePath: /
+    endpoints:
+      - path: /s
+        method: GET
+        sequence:
+          - responseBody: a1
+          - responseBody: a2
+  - name: b
+    host: b.test
+    basePath: /
+    endpoints:
+      - path: /s
+        method: GET
+        sequence:
+          - responseBody: b1
+          - responseBody: b2
+`
+
+func TestVirtualHostsRouteByHost(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": twoHostSequenceConfig}, routerOptions{})
+	expectBody(t, s.get("/s", "Host", "a.test"), "a1")
+	expectBody(t, s.get("/s", "Host", "B.TEST:8080"), "b1")
+	expectStatus(t, s.get("/s", "Host", "c.test"), 404)
+}
+
+// 同一路由在不同 host 上是不同的端点，序列进度不能共享
+func TestVirtualHostsKeepSeparateSequences(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": twoHostSequenceConfig}, routerOptions{})
+	expectBody(t, s.get("/s", "Host", "a.test"), "a1")
+	expectBody(t, s.get("/s", "Host", "b.test"), "b1")
+	expectBody(t, s.get("/s", "Host", "a.test"), "a2")
+	expectBody(t, s.get("/s", "Host", "b.test"), "b2")
+}
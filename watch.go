@@ -0,0 +1,146 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchSet 记录当前已监控的文件，重载配置后按差异增删监控
+type watchSet struct {
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+	watched map[string]bool
+	// pending 记录被删除或重命名、正在等待重新出现的文件
+	pending map[string]bool
+}
+
+func newWatchSet(watcher *fsnotify.Watcher) *watchSet {
+	return &watchSet{watcher: watcher, watched: make(map[string]bool), pending: make(map[string]bool)}
+}
+
+// sync 让监控集合与 paths 保持一致。同一文件被多个端点引用时只监控一次，
+// 只有在最后一个引用消失后才会移除
+func (w *watchSet) sync(paths []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	desired := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		desired[filepath.Clean(p)] = true
+	}
+
+	for p := range w.watched {
+		if desired[p] {
+			continue
+		}
+		if err := w.watcher.Remove(p); err != nil {
+			log.Printf("Failed to unwatch file %s: %v", p, err)
+		}
+		delete(w.watched, p)
+	}
+
+	// 等待恢复的文件不再被配置引用时停止等待，waitReplaced 会随之退出
+	for p := range w.pending {
+		if !desired[p] {
+			delete(w.pending, p)
+		}
+	}
+
+	for p := range desired {
+		w.add(p)
+	}
+}
+
+// add 开始监控 p，调用方需持有 mu
+func (w *watchSet) add(p string) {
+	if w.watched[p] || w.pending[p] {
+		return
+	}
+	if err := w.watcher.Add(p); err != nil {
+		log.Printf("Failed to watch file %s: %v", p, err)
+		return
+	}
+	w.watched[p] = true
+}
+
+const (
+	// replaceWait 是编辑器以重命名方式保存文件时，等待新文件出现的时间
+	replaceWait = time.Second
+	// removedPollInterval 是文件确实消失后检查它是否恢复的间隔
+	removedPollInterval = 2 * time.Second
+)
+
+// removed 处理监控文件的 Remove/Rename 事件。fsnotify 会随之丢弃该文件的监控，
+// 这里把它移出监控集合，以便之后 sync 重新添加到新的 inode 上。
+// 返回 false 表示该文件不在监控中，或已经在等待它恢复
+func (w *watchSet) removed(path string) bool {
+	path = filepath.Clean(path)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.watched[path] || w.pending[path] {
+		return false
+	}
+	delete(w.watched, path)
+	// 文件已经不存在时 Remove 会失败，忽略即可
+	w.watcher.Remove(path)
+	w.pending[path] = true
+	return true
+}
+
+// isPending 判断 path 是否仍在等待恢复
+func (w *watchSet) isPending(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pending[filepath.Clean(path)]
+}
+
+// waitReplaced 等待被删除的文件重新出现后调用 reload。文件很快出现说明是原子替换；
+// 否则记录警告，继续使用最后一次成功加载的配置，并定期检查文件是否恢复。
+// 期间重载后的配置不再引用该文件时，sync 会取消等待，这里直接返回
+func (w *watchSet) waitReplaced(path string, reload func() error) {
+	deadline := time.Now().Add(replaceWait)
+	warned := false
+	for {
+		if !w.isPending(path) {
+			log.Printf("Stopped waiting for %s, it is no longer referenced by the config", path)
+			return
+		}
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if !warned && time.Now().After(deadline) {
+			log.Printf("Warning: %s was removed or renamed, keeping the last loaded config until it reappears", path)
+			warned = true
+		}
+		if warned {
+			time.Sleep(removedPollInterval)
+		} else {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	w.mu.Lock()
+	delete(w.pending, filepath.Clean(path))
+	w.mu.Unlock()
+	if warned {
+		log.Printf("File restored: %s", path)
+	} else {
+		log.Printf("File replaced: %s", path)
+	}
+	// 重载失败时旧配置仍在使用，也要重新监控该文件，修正后才能再次触发重载
+	if err := reload(); err != nil {
+		log.Printf("Failed to reload config: %v", err)
+		w.rewatch(path)
+	}
+}
+
+// rewatch 重新监控恢复后的文件，文件已在监控中时不做任何事
+func (w *watchSet) rewatch(path string) {
+	w.mu.Lock()
+	w.add(filepath.Clean(path))
+	w.mu.Unlock()
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func watchedConfig(body string) string {
+	return `
+services:
+  - name: api
+    basePath: /
+    endpoints:
+      - path: /v
+        method: GET
+        responseBody: ` + body + "\n"
+}
+
+// nextEvent 等待 path 上满足 ops 的事件
+func nextEvent(t *testing.T, watcher *fsnotify.Watcher, path string, ops fsnotify.Op) fsnotify.Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-watcher.Events:
+			if filepath.Clean(event.Name) == path && event.Op&ops != 0 {
+				return event
+			}
+		case err := <-watcher.Errors:
+			t.Fatalf("watcher error: %v", err)
+		case <-timeout:
+			t.Fatalf("no %v event for %s", ops, path)
+		}
+	}
+}
+
+// 编辑器以“写临时文件再重命名覆盖”的方式保存时，旧 inode 的监控随之失效，
+// 需要重新监控新文件并重载，之后的修改仍能被发现
+func TestWatchRenameReplace(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": watchedConfig("v1")}, routerOptions{})
+	path := filepath.Join(s.dir, "config.yaml")
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	watches := newWatchSet(watcher)
+	watches.sync([]string{path})
+	reload := func() error {
+		if err := s.reload(); err != nil {
+			return err
+		}
+		watches.sync([]string{path})
+		return nil
+	}
+
+	s.write("config.yaml.tmp", watchedConfig("v2"))
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+	event := nextEvent(t, watcher, path, fsnotify.Remove|fsnotify.Rename)
+	if !watches.removed(event.Name) {
+		t.Fatalf("removed(%s) = false for a watched file", event.Name)
+	}
+	watches.waitReplaced(event.Name, reload)
+	expectBody(t, s.get("/v"), "v2")
+
+	// 新文件已重新监控，再次修改能收到事件
+	s.write("config.yaml", watchedConfig("v3"))
+	nextEvent(t, watcher, path, fsnotify.Write)
+	if err := reload(); err != nil {
+		t.Fatal(err)
+	}
+	expectBody(t, s.get("/v"), "v3")
+}
+
+// 文件确实被删除时保留最后一次成功加载的配置，文件恢复后再重载
+func TestWatchRemovedKeepsLastConfig(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": watchedConfig("v1")}, routerOptions{})
+	path := filepath.Join(s.dir, "config.yaml")
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	watches := newWatchSet(watcher)
+	watches.sync([]string{path})
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	event := nextEvent(t, watcher, path, fsnotify.Remove|fsnotify.Rename)
+	if !watches.removed(event.Name) {
+		t.Fatalf("removed(%s) = false for a watched file", event.Name)
+	}
+	// 等待期间重复的事件不会再启动一次等待
+	if watches.removed(event.Name) {
+		t.Fatalf("removed(%s) = true while already waiting", event.Name)
+	}
+	reloaded := make(chan struct{})
+	go watches.waitReplaced(event.Name, func() error {
+		defer close(reloaded)
+		return s.reload()
+	})
+	time.Sleep(100 * time.Millisecond)
+	expectBody(t, s.get("/v"), "v1")
+
+	s.write("config.yaml", watchedConfig("v2"))
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded after the file reappeared")
+	}
+	expectBody(t, s.get("/v"), "v2")
+}
+
+// 等待恢复期间重载的配置不再引用该文件时，等待的协程退出而不是一直轮询
+func TestWatchStopsWaitingForDroppedFile(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{"old.json": `{}`})
+	path := filepath.Join(dir, "old.json")
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	watches := newWatchSet(watcher)
+	watches.sync([]string{path})
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	event := nextEvent(t, watcher, path, fsnotify.Remove|fsnotify.Rename)
+	if !watches.removed(event.Name) {
+		t.Fatalf("removed(%s) = false for a watched file", event.Name)
+	}
+	done := make(chan struct{})
+	reloaded := false
+	go func() {
+		defer close(done)
+		watches.waitReplaced(event.Name, func() error {
+			reloaded = true
+			return nil
+		})
+	}()
+
+	watches.sync(nil)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitReplaced kept polling for a file the config no longer references")
+	}
+	if reloaded {
+		t.Fatal("reload called for a file that never came back")
+	}
+}
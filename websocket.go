@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsPongWait     = 60 * time.Second
+	wsPingInterval = 30 * time.Second
+	wsWriteWait    = 10 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{
+	// mock 服务不限制来源，方便浏览器页面直接连接
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsConn 让脚本回放、echo 和心跳共用一个连接时写操作互斥
+type wsConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *wsConn) write(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.WriteMessage(messageType, data)
+}
+
+// newWebSocketHandler 升级为 WebSocket 连接。配置了响应内容时按脚本依次发送消息
+// （格式与 SSE 事件列表相同），否则原样回显客户端消息
+func newWebSocketHandler(hc handlerContext, endpoint Endpoint) gin.HandlerFunc {
+	scripted := endpoint.ResponseFile != "" || endpoint.ResponseBody != ""
+
+	return func(c *gin.Context) {
+		var events []sseEvent
+		if scripted {
+			content := []byte(endpoint.ResponseBody)
+			if endpoint.ResponseFile != "" {
+				var err error
+				content, err = readJSONFile(endpoint.ResponseFile)
+				if err != nil {
+					hc.fileError(c, endpoint.ResponseFile, err)
+					return
+				}
+			}
+			var err error
+			events, err = parseSSEEvents(endpoint.ResponseFile, content)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		raw, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Printf("WebSocket upgrade failed for %s: %v", c.Request.URL.Path, err)
+			return
+		}
+		conn := &wsConn{Conn: raw}
+		defer conn.Close()
+
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+
+		// 读协程负责处理 pong 和关闭帧，echo 模式下同时回显消息
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				messageType, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				if !scripted {
+					if err := conn.write(messageType, data); err != nil {
+						return
+					}
+				}
+			}
+		}()
+
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+
+		// 只有脚本模式且有事件时才创建定时器；其余情况 timerC 为 nil，select 永远不会选中它
+		next := 0
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if scripted && len(events) > 0 {
+			timer = time.NewTimer(events[0].delay)
+			defer timer.Stop()
+			timerC = timer.C
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ping.C:
+				if err := conn.write(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case <-timerC:
+				if err := conn.write(websocket.TextMessage, []byte(events[next].data)); err != nil {
+					return
+				}
+				next++
+				if next < len(events) {
+					timer.Reset(events[next].delay)
+					continue
+				}
+				// 脚本发送完毕后正常关闭连接
+				conn.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+				}
+				return
+			}
+		}
+	}
+}
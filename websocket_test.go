@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const webSocketConfig = `
+services:
+  - name: ws
+    basePath: /
+    endpoints:
+      - path: /echo
+        method: GET
+        type: websocket
+      - path: /script
+        method: GET
+        type: websocket
+        responseBody: |
+          - data: first
+          - data: second
+            delay: 10ms
+`
+
+func dialWebSocket(t *testing.T, s *testServer, path string) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(s.handler)
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+path, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", path, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": webSocketConfig}, routerOptions{})
+	conn := dialWebSocket(t, s, "/echo")
+	for _, msg := range []string{"hello", "world"} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != msg {
+			t.Fatalf("echo = %q, want %q", data, msg)
+		}
+	}
+}
+
+func TestWebSocketScript(t *testing.T) {
+	s := newTestServer(t, map[string]string{"config.yaml": webSocketConfig}, routerOptions{})
+	conn := dialWebSocket(t, s, "/script")
+	for _, want := range []string{"first", "second"} {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("message = %q, want %q", data, want)
+		}
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("after script err = %v, want normal closure", err)
+	}
+}